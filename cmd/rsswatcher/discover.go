@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rsswatcher/rsswatcher/internal/fetcher"
+)
+
+// runDiscover 打印网站地址对应的订阅源候选
+func runDiscover(args []string) int {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: rsswatcher discover <url>")
+		return 2
+	}
+
+	candidates, err := fetcher.New().Discover(context.Background(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "discover: %v\n", err)
+		return 1
+	}
+
+	for _, c := range candidates {
+		line := c.URL
+		if c.Type != "" {
+			line += "\t" + c.Type
+		}
+		if c.Title != "" {
+			line += "\t" + c.Title
+		}
+		fmt.Printf("%s\t(%s)\n", line, c.Source)
+	}
+	return 0
+}
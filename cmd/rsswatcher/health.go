@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/health"
+	"github.com/rsswatcher/rsswatcher/internal/notifier"
+	"github.com/rsswatcher/rsswatcher/internal/report"
+	"github.com/rsswatcher/rsswatcher/internal/state"
+)
+
+// checkHealth 根据本次抓取和解析的结果更新订阅源的健康状况，
+// 需要时发送故障告警或恢复通知。关闭通知的订阅源只记录健康状况。
+// 至少一个渠道收到通知即视为已发送，所有渠道都失败时下次运行重试。
+func (w *watcher) checkHealth(feed config.Feed, res *report.FeedResult, logger *slog.Logger) {
+	h := w.state.Health(feed.ID)
+	now := time.Now()
+	switch res.Status {
+	case report.StatusOK, report.StatusNotModified:
+		health.RecordSuccess(&h, now, res.NewItems)
+	case report.StatusFailed:
+		health.RecordFailure(&h, now, res.Error)
+	default:
+		// 跳过、已停用或中断的订阅源本次没有完成抓取
+		return
+	}
+	defer func() { w.state.SetHealth(feed.ID, h) }()
+
+	if !feed.Notify {
+		return
+	}
+	policy := health.Policy{MaxFailures: feed.AlertAfterFailures, StaleAfter: time.Duration(feed.StaleAfter)}
+	e := health.Check(h, policy, now)
+
+	if e.Recovered != "" {
+		if !w.sendAlert(feed, "Feed recovered", recoveredMessage(e.Recovered), logger) {
+			return
+		}
+		health.Ack(&h, health.Event{Recovered: e.Recovered})
+		res.Alert = "recovered"
+	}
+	if e.Alert != "" {
+		title, body := alertMessage(h, e.Alert, now)
+		if !w.sendAlert(feed, title, body, logger) {
+			return
+		}
+		health.Ack(&h, e)
+		res.Alert = e.Alert
+	}
+}
+
+// sendAlert 把健康通知发送到订阅源的渠道，返回是否至少一个渠道收到
+func (w *watcher) sendAlert(feed config.Feed, title, body string, logger *slog.Logger) bool {
+	sent, err := notifier.Alert(w.notifiers[feed.ID], feed.Name, title, body, configuredURL(feed))
+	if err != nil {
+		logger.Error("failed to send health alert", "stage", "notify", "alert", title, "delivered", sent, "error", err)
+	}
+	if sent == 0 {
+		return false
+	}
+	logger.Warn("sent health alert", "stage", "notify", "alert", title)
+	return true
+}
+
+func alertMessage(h state.Health, alert string, now time.Time) (title, body string) {
+	if alert == health.Failing {
+		return fmt.Sprintf("Feed failing: %d consecutive failures", h.ConsecutiveFailures), h.LastError
+	}
+	return fmt.Sprintf("No new items for %s", formatAge(now.Sub(*h.LastNewItem))),
+		"Last new item: " + h.LastNewItem.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func recoveredMessage(alert string) string {
+	if alert == health.Failing {
+		return "Fetching works again."
+	}
+	return "New items are arriving again."
+}
+
+// formatAge 以天或小时粗略显示时长
+func formatAge(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%d hours", int(d/time.Hour))
+}
@@ -2,179 +2,354 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
-	"strings"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/rsswatcher/rsswatcher/internal/archive"
 	"github.com/rsswatcher/rsswatcher/internal/config"
-	"github.com/rsswatcher/rsswatcher/internal/deduper"
 	"github.com/rsswatcher/rsswatcher/internal/env"
-	"github.com/rsswatcher/rsswatcher/internal/fetcher"
-	"github.com/rsswatcher/rsswatcher/internal/notifier"
-	"github.com/rsswatcher/rsswatcher/internal/parser"
+	"github.com/rsswatcher/rsswatcher/internal/logging"
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
+	"github.com/rsswatcher/rsswatcher/internal/output"
+	"github.com/rsswatcher/rsswatcher/internal/report"
+	"github.com/rsswatcher/rsswatcher/internal/scheduler"
 	"github.com/rsswatcher/rsswatcher/internal/state"
 	"github.com/rsswatcher/rsswatcher/internal/summarizer"
 )
 
-const maxConcurrent = 8
+const (
+	maxConcurrent   = 8
+	defaultInterval = 30 * time.Minute
+	pollJitter      = 0.1
+	// pollSlack 是按订阅源声明的更新间隔推迟抓取时预留的余量比例
+	pollSlack = 0.1
+	// maxPollDelay 是更新间隔或 Retry-After 最多推迟抓取的时间
+	maxPollDelay = 24 * time.Hour
+)
+
+// commands 是除默认运行模式外的子命令，参数不含子命令名本身
+var commands = map[string]func(args []string) int{
+	"validate": runValidate,
+	"opml":     runOPML,
+	"discover": runDiscover,
+	"state":    runState,
+	"search":   runSearch,
+}
 
 func main() {
 	// 加载 .env 文件（如果存在）
 	// 这允许本地开发时使用 .env 文件，而不影响生产环境
-	if err := env.LoadEnvDefault(); err != nil {
-		log.Printf("Warning: Failed to load .env file: %v", err)
+	envErr := env.LoadEnvDefault()
+
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
 	}
 
 	configPath := flag.String("config", "feeds.yaml", "Path to feeds configuration file")
-	statePath := flag.String("state", "state/last_states.json", "Path to state file")
+	statePath := flag.String("state", "state/last_states.json", "State location: a JSON file path or sqlite:<path>")
+	daemon := flag.Bool("daemon", false, "Keep running and poll each feed on its own interval")
+	interval := flag.Duration("interval", defaultInterval, "Default polling interval in daemon mode for feeds without one")
+	cacheDir := flag.String("summary-cache", "state/summary_cache", "Directory for cached AI summaries (empty to disable)")
+	cacheTTL := flag.Duration("summary-cache-ttl", 30*24*time.Hour, "How long cached summaries stay valid")
+	cacheSize := flag.Int("summary-cache-size", 1000, "Maximum number of cached summaries")
+	archivePath := flag.String("archive", "state/archive.db", "SQLite database that keeps processed items for search (empty to disable)")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address in daemon mode, e.g. :9090")
+	reportPath := flag.String("report", "", "Write a JSON report of each feed's results to this file after the run")
+	dryRun := flag.Bool("dry-run", false, "Run the full pipeline but print notifications instead of sending them and don't save state")
+	feedID := flag.String("feed", "", "Only process the feed with this ID")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	hostConcurrency := flag.Int("host-concurrency", 2, "Maximum concurrent requests to the same host")
+	hostDelay := flag.Duration("host-delay", time.Second, "Minimum delay between requests to the same host")
+	updateConfig := flag.Bool("update-config", false, "Rewrite the url of permanently moved feeds in the config file")
 	flag.Parse()
 
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	if envErr != nil {
+		slog.Warn("failed to load .env file", "error", envErr)
+	}
+
 	// Load configuration
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("failed to load config", err)
+	}
+
+	if *feedID != "" {
+		feed, ok := findFeed(cfg, *feedID)
+		if !ok {
+			fatal("unknown feed", fmt.Errorf("no feed with id %q in %s", *feedID, *configPath))
+		}
+		cfg.Feeds = []config.Feed{feed}
 	}
 
 	if len(cfg.Feeds) == 0 {
-		log.Println("No feeds configured")
+		slog.Info("no feeds configured")
 		return
 	}
 
+	if *dryRun && *daemon {
+		fatal("invalid flags", errors.New("--dry-run cannot be combined with --daemon"))
+	}
+
 	// Load state
-	s, err := state.Load(*statePath)
+	store, err := state.Open(*statePath)
+	if err != nil {
+		fatal("failed to open state", err)
+	}
+	defer store.Close()
+
+	s, err := store.Load()
 	if err != nil {
-		log.Fatalf("Failed to load state: %v", err)
+		fatal("failed to load state", err)
 	}
 
 	// Initialize components
-	fetcher := fetcher.New()
-	parser := parser.New()
-	deduper := deduper.New(s)
-	notifier := notifier.NewBark()
-	summarizer := summarizer.New()
+	w, err := newWatcher(cfg, s, *dryRun)
+	if err != nil {
+		fatal("failed to initialize", err)
+	}
+
+	w.fetcher.LimitHosts(*hostConcurrency, *hostDelay)
+
+	if *cacheDir != "" {
+		w.cache = summarizer.NewCache(*cacheDir, *cacheTTL, *cacheSize)
+	}
+
+	// 试运行不发送通知，也不归档
+	if *archivePath != "" && !*dryRun {
+		a, err := archive.Open(*archivePath)
+		if err != nil {
+			fatal("failed to open archive", err)
+		}
+		defer a.Close()
+		w.archive = a
+	}
+
+	if out := cfg.Output; out.Path != "" {
+		if *dryRun {
+			slog.Info("dry run, output feed not written", "path", out.Path)
+		} else {
+			w.output = output.New(output.Options{
+				Path:     out.Path,
+				Format:   out.Format,
+				Title:    out.Title,
+				Link:     out.Link,
+				MaxItems: out.MaxItems,
+				Order:    out.Order,
+			})
+		}
+	}
 
 	// Log summarizer status
-	if summarizer.IsEnabled() {
-		log.Println("AI summarizer is enabled")
+	if w.summarizer.IsEnabled() {
+		slog.Info("AI summarizer is enabled")
+	} else {
+		slog.Info("AI summarizer is disabled (missing API_ENDPOINT, API_KEY, or MODEL_NAME)")
+	}
+
+	rep := report.New(time.Now())
+
+	// 为空时不改写配置文件
+	updatePath := ""
+	if *updateConfig {
+		if *dryRun {
+			slog.Warn("ignoring --update-config in dry run")
+		} else {
+			updatePath = *configPath
+		}
+	}
+
+	if *daemon {
+		runDaemon(w, cfg, s, store, *statePath, *interval, rep, *reportPath, *metricsAddr, updatePath)
+		return
+	}
+
+	if *metricsAddr != "" {
+		slog.Warn("ignoring --metrics-addr outside daemon mode")
+	}
+
+	runOnce(w, cfg, rep)
+	writeOutput(w.output)
+	if *dryRun {
+		slog.Info("dry run, state not saved")
 	} else {
-		log.Println("AI summarizer is disabled (missing API_ENDPOINT, API_KEY, or MODEL_NAME)")
+		saveState(store, s, *statePath)
 	}
+	if updatePath != "" {
+		updateMovedFeeds(cfg, s, updatePath)
+	}
+	writeReport(rep, *reportPath)
+}
 
+func findFeed(cfg *config.Config, id string) (config.Feed, bool) {
+	for _, feed := range cfg.Feeds {
+		if feed.ID == id {
+			return feed, true
+		}
+	}
+	return config.Feed{}, false
+}
+
+// runOnce 并发处理所有订阅源一次
+func runOnce(w *watcher, cfg *config.Config, rep *report.Report) {
 	// Process feeds concurrently with semaphore
 	sem := make(chan struct{}, maxConcurrent)
 	var wg sync.WaitGroup
 
 	for _, feed := range cfg.Feeds {
-		// Set defaults
-		if feed.DedupeKey == "" {
-			feed.DedupeKey = "guid"
-		}
-		if feed.Notify {
-			feed.Notify = true
-		}
-
 		wg.Add(1)
 		go func(f config.Feed) {
 			defer wg.Done()
 			sem <- struct{}{}        // Acquire
 			defer func() { <-sem }() // Release
 
-			processFeed(context.Background(), f, fetcher, parser, deduper, notifier, summarizer)
+			rep.Record(w.processFeed(context.Background(), f))
 		}(feed)
 	}
 
 	wg.Wait()
-
-	// Save state
-	if err := s.Save(*statePath); err != nil {
-		log.Printf("Failed to save state: %v", err)
-	} else {
-		log.Printf("State saved to %s", *statePath)
-	}
 }
 
-func processFeed(ctx context.Context, feed config.Feed, fetcher *fetcher.Fetcher, parser *parser.Parser, deduper *deduper.Deduper, notifier *notifier.BarkNotifier, summarizer *summarizer.Summarizer) {
-	log.Printf("Processing feed: %s (%s)", feed.Name, feed.ID)
+// runDaemon 持续运行，按各订阅源的间隔轮询，直到收到 SIGINT/SIGTERM。
+// 报告保存每个订阅源最近一次的结果，每次轮询后重写。
+func runDaemon(w *watcher, cfg *config.Config, s *state.State, store state.Store, statePath string, defaultInterval time.Duration, rep *report.Report, reportPath, metricsAddr, updatePath string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	w.shutdown = ctx
 
-	// Fetch feed
-	data, err := fetcher.Fetch(ctx, feed.URL)
-	if err != nil {
-		log.Printf("Failed to fetch %s: %v", feed.Name, err)
-		return
+	if metricsAddr != "" {
+		serveMetrics(ctx, metricsAddr)
 	}
 
-	// Parse feed
-	items, err := parser.Parse(data)
-	if err != nil {
-		log.Printf("Failed to parse %s: %v", feed.Name, err)
-		return
+	jobs := make([]scheduler.Job, 0, len(cfg.Feeds))
+	for _, feed := range cfg.Feeds {
+		interval := time.Duration(feed.Interval)
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		jobs = append(jobs, scheduler.Job{
+			Name:     feed.ID,
+			Interval: interval,
+			Run:      func(ctx context.Context) { rep.Record(w.processFeed(ctx, feed)) },
+		})
+		slog.Info("scheduling feed", "feed_id", feed.ID, "interval", interval.String())
 	}
 
-	if len(items) == 0 {
-		log.Printf("No items found in %s", feed.Name)
+	// 各订阅源可能同时完成，串行化状态保存
+	var saveMu sync.Mutex
+	scheduler.Run(ctx, jobs, scheduler.Options{
+		MaxConcurrent: maxConcurrent,
+		Jitter:        pollJitter,
+		AfterRun: func(scheduler.Job) {
+			saveMu.Lock()
+			defer saveMu.Unlock()
+			saveState(store, s, statePath)
+			if updatePath != "" {
+				updateMovedFeeds(cfg, s, updatePath)
+			}
+			writeOutput(w.output)
+			writeReport(rep, reportPath)
+		},
+	})
+
+	slog.Info("shutting down")
+	saveState(store, s, statePath)
+	writeOutput(w.output)
+	writeReport(rep, reportPath)
+}
+
+// serveMetrics 在 addr 上提供 /metrics 接口，ctx 取消时关闭
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		slog.Info("serving metrics", "addr", addr, "path", "/metrics")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "error", err)
+		}
+	}()
+}
+
+// writeReport 写入运行报告，path 为空时不写
+func writeReport(rep *report.Report, path string) {
+	if path == "" {
 		return
 	}
+	if err := rep.Write(path, time.Now()); err != nil {
+		slog.Error("failed to write report", "path", path, "error", err)
+	}
+}
 
-	// Deduplicate
-	newItems := deduper.GetNewItems(feed.ID, items, feed.DedupeKey)
-	if len(newItems) == 0 {
-		log.Printf("No new items in %s", feed.Name)
+// writeOutput 把本次收集的新条目写入合并输出订阅源，out 为 nil 时不写
+func writeOutput(out *output.Writer) {
+	if out == nil {
 		return
 	}
+	n, err := out.Flush(time.Now())
+	if err != nil {
+		slog.Error("failed to write output feed", "error", err)
+	} else if n > 0 {
+		slog.Info("output feed written", "items", n)
+	}
+}
 
-	log.Printf("Found %d new items in %s", len(newItems), feed.Name)
-
-	// Generate summaries if enabled
-	if summarizer.IsEnabled() {
-		log.Printf("Generating summaries for %s (%d items)...", feed.Name, len(newItems))
-		successCount := 0
-		for i, item := range newItems {
-			log.Printf("  [%d/%d] Generating summary for: %s", i+1, len(newItems), item.Title)
-			summary, err := summarizer.Summarize(ctx, item.Title, item.Description)
-			if err != nil {
-				log.Printf("  ❌ Failed to generate summary for '%s': %v", item.Title, err)
-				log.Printf("  → Using original description instead")
-				// 总结失败时使用原始描述
-				item.Summary = ""
-			} else {
-				item.Summary = summary
-				successCount++
-				log.Printf("  ✅ Generated summary (%d chars): %s", len(summary), truncateSummary(summary, 50))
-			}
+// updateMovedFeeds 把永久迁移的订阅源地址写回配置文件。
+// 守护进程模式下内存中的配置不变，状态中记录的迁移继续生效。
+func updateMovedFeeds(cfg *config.Config, s *state.State, path string) {
+	var changes []config.URLChange
+	for _, feed := range cfg.Feeds {
+		if from, to := s.Moved(feed.ID); to != "" && from == configuredURL(feed) {
+			changes = append(changes, config.URLChange{FeedID: feed.ID, From: from, To: to})
 		}
-		log.Printf("Summary generation complete: %d/%d succeeded for %s", successCount, len(newItems), feed.Name)
-	} else {
-		log.Printf("AI summarizer disabled, skipping summary generation for %s", feed.Name)
 	}
-
-	// Send notifications
-	if !feed.Notify {
-		log.Printf("Notifications disabled for %s", feed.Name)
+	if len(changes) == 0 {
 		return
 	}
 
-	if feed.Aggregate {
-		if err := notifier.NotifyAggregate(feed.Name, newItems); err != nil {
-			log.Printf("Failed to send aggregate notification for %s: %v", feed.Name, err)
-		} else {
-			log.Printf("Sent aggregate notification for %s (%d items)", feed.Name, len(newItems))
-		}
-	} else {
-		if err := notifier.Notify(feed.Name, newItems); err != nil {
-			log.Printf("Failed to send notifications for %s: %v", feed.Name, err)
-		} else {
-			log.Printf("Sent %d notifications for %s", len(newItems), feed.Name)
-		}
+	n, err := config.UpdateFeedURLs(path, changes)
+	if err != nil {
+		slog.Error("failed to update config", "path", path, "error", err)
+	} else if n > 0 {
+		slog.Info("updated moved feed urls in config", "path", path, "feeds", n)
 	}
 }
 
-// truncateSummary 截断摘要用于日志显示
-func truncateSummary(s string, maxLen int) string {
-	s = strings.TrimSpace(s)
-	runes := []rune(s)
-	if len(runes) <= maxLen {
-		return s
+func saveState(store state.Store, s *state.State, path string) {
+	if err := store.Save(s); err != nil {
+		slog.Error("failed to save state", "path", path, "error", err)
+	} else {
+		slog.Info("state saved", "path", path)
 	}
-	return string(runes[:maxLen]) + "..."
+}
+
+// fatal 记录错误后退出
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
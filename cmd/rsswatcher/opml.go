@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/opml"
+)
+
+const opmlUsage = `usage:
+  rsswatcher opml import [--config feeds.yaml] file.opml
+  rsswatcher opml export [--config feeds.yaml] [--output file.opml]`
+
+func runOPML(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, opmlUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "import":
+		return runOPMLImport(args[1:])
+	case "export":
+		return runOPMLExport(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, opmlUsage)
+		return 2
+	}
+}
+
+// runOPMLImport 把 OPML 中的新订阅源追加到配置文件，已有订阅源保持不变
+func runOPMLImport(args []string) int {
+	fs := flag.NewFlagSet("opml import", flag.ExitOnError)
+	configPath := fs.String("config", "feeds.yaml", "Path to feeds configuration file")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, opmlUsage)
+		return 2
+	}
+
+	cfg := &config.Config{}
+	if _, err := os.Stat(*configPath); err == nil {
+		if cfg, err = config.Load(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open OPML: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	doc, err := opml.Parse(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+
+	entries := doc.Entries()
+	added, rejected := opml.Merge(cfg, entries)
+	if len(added) > 0 {
+		if err := config.AppendFeeds(*configPath, added); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write config: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, feed := range added {
+		fmt.Printf("+ %s (%s)\n", feed.ID, feed.URL)
+	}
+	for _, r := range rejected {
+		fmt.Fprintf(os.Stderr, "! skipped %q (%s): %v\n", r.Entry.Title, r.Entry.URL, r.Err)
+	}
+	fmt.Printf("Imported %d of %d feeds into %s (%d already present, %d invalid)\n",
+		len(added), len(entries), *configPath, len(entries)-len(added)-len(rejected), len(rejected))
+	return 0
+}
+
+func runOPMLExport(args []string) int {
+	fs := flag.NewFlagSet("opml export", flag.ExitOnError)
+	configPath := fs.String("config", "feeds.yaml", "Path to feeds configuration file")
+	output := fs.String("output", "", "Write OPML to this file instead of stdout")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *output, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := opml.Export(w, cfg, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export OPML: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/archive"
+)
+
+// runSearch 在归档中搜索条目。没有搜索词时按 --feed、--since 和 --until 列出条目
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	archivePath := fs.String("archive", "state/archive.db", "Archive database written by the watcher")
+	feedID := fs.String("feed", "", "Only show items from the feed with this ID")
+	since := fs.String("since", "", "Only show items published on or after this date (YYYY-MM-DD) or within this duration, e.g. 7d or 720h")
+	until := fs.String("until", "", "Only show items published on or before this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", 20, "Maximum number of results (0 for all)")
+	fs.Parse(args)
+
+	now := time.Now()
+	q := archive.Query{Text: strings.Join(fs.Args(), " "), FeedID: *feedID, Limit: *limit}
+	var err error
+	if *since != "" {
+		if q.Since, err = parseSince(*since, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --since: %v\n", err)
+			return 2
+		}
+	}
+	if *until != "" {
+		t, err := time.ParseInLocation(time.DateOnly, *until, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --until: %v\n", err)
+			return 2
+		}
+		// 包含当天
+		q.Until = t.AddDate(0, 0, 1)
+	}
+
+	if _, err := os.Stat(*archivePath); err != nil {
+		fmt.Fprintf(os.Stderr, "No archive at %s: %v\n", *archivePath, err)
+		return 1
+	}
+	a, err := archive.Open(*archivePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open archive: %v\n", err)
+		return 1
+	}
+	defer a.Close()
+
+	entries, err := a.Search(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search failed: %v\n", err)
+		return 1
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s  [%s] %s (%s)\n", e.Published.Local().Format(time.DateOnly), e.FeedID, e.Title, e.Notified)
+		text := e.Summary
+		if text == "" {
+			text = e.Description
+		}
+		if text != "" {
+			fmt.Printf("    %s\n", text)
+		}
+		if e.Link != "" {
+			fmt.Printf("    %s\n", e.Link)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d result(s)\n", len(entries))
+	return 0
+}
+
+// parseSince 解析日期（当天零点起）或时长（从现在往前推），时长可以用 d 表示天数
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.ParseInLocation(time.DateOnly, v, time.Local)
+}
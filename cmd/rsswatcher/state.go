@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/state"
+)
+
+const stateUsage = `usage:
+  rsswatcher state migrate --from state/last_states.json --to sqlite:state/state.db
+  rsswatcher state history [--state sqlite:state/state.db] [--limit 10] feed-id`
+
+func runState(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runStateMigrate(args[1:])
+	case "history":
+		return runStateHistory(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, stateUsage)
+		return 2
+	}
+}
+
+// runStateMigrate 把一个存储中的全部状态复制到另一个存储
+func runStateMigrate(args []string) int {
+	fs := flag.NewFlagSet("state migrate", flag.ExitOnError)
+	from := fs.String("from", "", "State to copy from: a JSON file path or sqlite:<path>")
+	to := fs.String("to", "", "State to copy to: a JSON file path or sqlite:<path>")
+	fs.Parse(args)
+	if *from == "" || *to == "" || fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		return 2
+	}
+
+	src, err := state.Open(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", *from, err)
+		return 1
+	}
+	defer src.Close()
+
+	dst, err := state.Open(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", *to, err)
+		return 1
+	}
+	defer dst.Close()
+
+	n, err := state.Migrate(src, dst)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to migrate state: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Migrated %d feeds from %s to %s\n", n, *from, *to)
+	return 0
+}
+
+// runStateHistory 按从新到旧的顺序输出订阅源状态的历史版本，每行一个 JSON
+func runStateHistory(args []string) int {
+	fs := flag.NewFlagSet("state history", flag.ExitOnError)
+	statePath := fs.String("state", "sqlite:state/state.db", "SQLite state store, as sqlite:<path>")
+	limit := fs.Int("limit", 10, "Maximum number of revisions to show (0 for all)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		return 2
+	}
+
+	store, err := state.Open(*statePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", *statePath, err)
+		return 1
+	}
+	defer store.Close()
+
+	db, ok := store.(*state.SQLiteStore)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: history is only kept by the SQLite store\n", *statePath)
+		return 1
+	}
+
+	revs, err := db.History(fs.Arg(0), *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+		return 1
+	}
+
+	for _, rev := range revs {
+		data, err := json.Marshal(rev.Feed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode revision: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s\t%s\n", rev.SavedAt.Local().Format(time.RFC3339), data)
+	}
+	return 0
+}
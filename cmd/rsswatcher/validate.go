@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+)
+
+// runValidate 校验配置文件，存在错误时以非零状态退出
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := fs.String("config", "feeds.yaml", "Path to feeds configuration file")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		var errs config.Errors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", *configPath, e)
+			}
+			fmt.Fprintf(os.Stderr, "%d error(s) found\n", len(errs))
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		}
+		return 1
+	}
+
+	fmt.Printf("%s: OK (%d feeds, %d channels)\n", *configPath, len(cfg.Feeds), len(cfg.Channels))
+	return 0
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/archive"
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/deduper"
+	"github.com/rsswatcher/rsswatcher/internal/extract"
+	"github.com/rsswatcher/rsswatcher/internal/fetcher"
+	"github.com/rsswatcher/rsswatcher/internal/filter"
+	"github.com/rsswatcher/rsswatcher/internal/media"
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
+	"github.com/rsswatcher/rsswatcher/internal/notifier"
+	"github.com/rsswatcher/rsswatcher/internal/output"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+	"github.com/rsswatcher/rsswatcher/internal/report"
+	"github.com/rsswatcher/rsswatcher/internal/state"
+	"github.com/rsswatcher/rsswatcher/internal/summarizer"
+)
+
+// watcher 持有处理订阅源所需的全部组件
+type watcher struct {
+	state      *state.State
+	fetcher    *fetcher.Fetcher
+	parser     *parser.Parser
+	deduper    *deduper.Deduper
+	summarizer *summarizer.Summarizer
+	cache      *summarizer.Cache            // 为 nil 时不缓存总结
+	archive    *archive.Archive             // 为 nil 时不归档条目
+	output     *output.Writer               // 为 nil 时不输出合并订阅源
+	notifiers  map[string]notifier.Notifier // 按 feed ID 索引
+	filters    map[string]*filter.Filter    // 按 feed ID 索引
+	dryRun     bool                         // 只打印通知，不写入总结缓存
+	// shutdown 在守护进程收到退出信号时取消，用于中断附件下载，为 nil 时不中断
+	shutdown context.Context
+}
+
+// newWatcher 创建 watcher。dryRun 为 true 时所有渠道替换为打印到标准输出。
+func newWatcher(cfg *config.Config, s *state.State, dryRun bool) (*watcher, error) {
+	channels, err := buildChannels(cfg.Channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up notification channels: %w", err)
+	}
+	if dryRun {
+		var mu sync.Mutex
+		for i, ch := range channels {
+			channels[i].Notifier = notifier.NewPrinter(os.Stdout, &mu, ch.Name)
+		}
+	}
+
+	notifiers := make(map[string]notifier.Notifier, len(cfg.Feeds))
+	filters := make(map[string]*filter.Filter, len(cfg.Feeds))
+	for _, feed := range cfg.Feeds {
+		n, err := feedNotifier(feed, channels)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: %w", feed.ID, err)
+		}
+		notifiers[feed.ID] = n
+
+		f, err := filter.New(feed.Filters)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: filters: %w", feed.ID, err)
+		}
+		filters[feed.ID] = f
+	}
+
+	return &watcher{
+		state:      s,
+		fetcher:    fetcher.New(),
+		parser:     parser.New(),
+		deduper:    deduper.New(s),
+		summarizer: summarizer.New(),
+		notifiers:  notifiers,
+		filters:    filters,
+		dryRun:     dryRun,
+	}, nil
+}
+
+// processFeed 处理单个订阅源并返回本次的统计结果
+func (w *watcher) processFeed(ctx context.Context, feed config.Feed) (res report.FeedResult) {
+	logger := slog.With("feed_id", feed.ID)
+	logger.Info("processing feed", "name", feed.Name)
+
+	res = report.FeedResult{ID: feed.ID, Name: feed.Name, Status: report.StatusOK, StartedAt: time.Now()}
+	defer func() { res.DurationMS = time.Since(res.StartedAt).Milliseconds() }()
+
+	ctx = fetcher.WithFeedID(ctx, feed.ID)
+
+	newItems := w.collectNewItems(ctx, feed, &res, logger)
+	// 此时的失败只可能来自抓取、发现或解析，通知失败不影响健康状况
+	w.checkHealth(feed, &res, logger)
+
+	if w.output != nil && len(newItems) > 0 {
+		w.output.Add(feed.ID, feed.Name, feed.DedupeKey, newItems, time.Now())
+	}
+
+	// Send notifications
+	if feed.Notify {
+		w.deliver(feed, newItems, &res, logger)
+	} else if len(newItems) > 0 {
+		logger.Info("notifications disabled", "stage", "notify", "new_items", len(newItems))
+		w.archiveItems(feed, newItems, archive.Off, logger)
+	}
+
+	if feed.MediaDownloadDir != "" && !w.dryRun {
+		w.downloadMedia(ctx, feed, newItems, &res, logger)
+	}
+	return res
+}
+
+// collectNewItems 抓取、解析、去重并生成总结，返回本次的新条目
+func (w *watcher) collectNewItems(ctx context.Context, feed config.Feed, res *report.FeedResult, logger *slog.Logger) []*parser.Item {
+	if u, at := w.state.Disabled(feed.ID); u != "" {
+		if u == configuredURL(feed) {
+			logger.Warn("feed disabled after 410 Gone, change its url to re-enable", "stage", "fetch", "url", u, "disabled_at", at.Format(time.RFC3339))
+			res.Status = report.StatusDisabled
+			return nil
+		}
+		logger.Info("feed url changed, re-enabling", "stage", "fetch", "old_url", u)
+		w.state.Enable(feed.ID)
+	}
+	// 配置中的地址已改变（包括 --update-config 写回了新地址）时，记录的迁移不再需要
+	if from, to := w.state.Moved(feed.ID); to != "" && from != configuredURL(feed) {
+		if to != configuredURL(feed) {
+			logger.Info("feed url changed, dropping recorded redirect", "stage", "fetch", "old_url", from, "moved_to", to)
+		}
+		w.state.SetMoved(feed.ID, "", "")
+	}
+
+	// 试运行不保存状态，总是抓取
+	if next := w.state.NextPoll(feed.ID); !w.dryRun && res.StartedAt.Before(next) {
+		logger.Info("skipping feed until next poll time", "stage", "fetch", "next_poll", next.Format(time.RFC3339))
+		res.Status = report.StatusSkipped
+		return nil
+	}
+
+	// Fetch feed
+	etag, lastModified := w.state.Validators(feed.ID)
+	fetchURL := w.feedURL(feed)
+	resp, err := w.fetcher.Fetch(ctx, fetchURL, fetcher.Validators{ETag: etag, LastModified: lastModified})
+	if err != nil && canceled(ctx, res, "fetch", logger) {
+		return nil
+	}
+	if err != nil {
+		logger.Error("fetch failed", "stage", "fetch", "error", err)
+		res.Fail("fetch", err)
+		var httpErr *fetcher.HTTPError
+		if errors.As(err, &httpErr) {
+			res.HTTPStatus = httpErr.StatusCode
+			if httpErr.StatusCode == http.StatusGone {
+				logger.Error("feed is gone, disabling it", "stage", "fetch", "url", configuredURL(feed))
+				w.state.Disable(feed.ID, configuredURL(feed), res.StartedAt)
+			}
+			if httpErr.RetryAfter > 0 {
+				next := res.StartedAt.Add(min(httpErr.RetryAfter, maxPollDelay))
+				logger.Warn("server asked to retry later", "stage", "fetch", "next_poll", next.Format(time.RFC3339))
+				w.state.SetNextPoll(feed.ID, next)
+			}
+		}
+		// 自动发现的地址可能已失效，清除后下次重新发现；暂时性错误保留该地址
+		if !fetcher.IsTransient(err) {
+			w.state.SetDiscoveredURL(feed.ID, "")
+		}
+		return nil
+	}
+	res.HTTPStatus = resp.StatusCode
+	if resp.PermanentURL != "" {
+		w.recordMove(feed, fetchURL, resp.PermanentURL, res, logger)
+	}
+
+	if resp.NotModified {
+		logger.Info("feed not modified since last fetch", "stage", "fetch")
+		res.Status = report.StatusNotModified
+		metrics.LastSuccess.Set(float64(time.Now().Unix()), feed.ID)
+		// 304 也可能带有新的校验值
+		w.state.SetValidators(feed.ID, resp.Validators.ETag, resp.Validators.LastModified)
+		w.scheduleNextPoll(feed, res.StartedAt, w.state.UpdateInterval(feed.ID))
+		return nil
+	}
+
+	// 返回的是网页而不是订阅源时，从网页中自动发现订阅源地址
+	if fetcher.IsHTML(resp.ContentType, resp.Body) {
+		resp, err = w.discover(ctx, feed, resp, logger)
+		if err != nil && canceled(ctx, res, "discover", logger) {
+			return nil
+		}
+		if err != nil {
+			logger.Error("feed discovery failed", "stage", "discover", "error", err)
+			res.Fail("discover", err)
+			return nil
+		}
+		res.HTTPStatus = resp.StatusCode
+	}
+
+	// Parse feed
+	parsed, err := w.parser.ParseFeed(resp.Body)
+	if err != nil {
+		logger.Error("parse failed", "stage", "parse", "error", err)
+		res.Fail("parse", err)
+		w.state.SetDiscoveredURL(feed.ID, "")
+		return nil
+	}
+	items := parsed.Items
+	res.ItemsParsed = len(items)
+	metrics.LastSuccess.Set(float64(time.Now().Unix()), feed.ID)
+
+	// 解析成功后才保存校验值，否则下次会收到 304 而跳过重试
+	w.state.SetValidators(feed.ID, resp.Validators.ETag, resp.Validators.LastModified)
+	w.state.SetUpdateInterval(feed.ID, parsed.UpdateInterval)
+	w.scheduleNextPoll(feed, res.StartedAt, parsed.UpdateInterval)
+
+	if len(items) == 0 {
+		logger.Info("no items found", "stage", "parse")
+		return nil
+	}
+
+	// Deduplicate
+	newItems := w.deduper.GetNewItems(feed.ID, items, feed.DedupeKey, feed.SeenRetention)
+	res.NewItems = len(newItems)
+	if len(newItems) == 0 {
+		logger.Info("no new items", "stage", "dedupe", "items", len(items))
+		return nil
+	}
+
+	logger.Info("found new items", "stage", "dedupe", "items", len(items), "new_items", len(newItems))
+	for _, item := range newItems {
+		logger.Debug("new item", "stage", "dedupe", "item_guid", item.GUID, "title", item.Title)
+	}
+
+	// 被过滤的条目已由 deduper 记为已见，不会在下次重复出现
+	newItems, filtered := w.filters[feed.ID].Apply(newItems)
+	res.FilteredItems = filtered
+	if filtered > 0 {
+		logger.Info("filtered out items", "stage", "filter", "filtered", filtered, "remaining", len(newItems))
+	}
+	if feed.Media {
+		var skipped []*parser.Item
+		newItems, skipped = mediaItems(newItems)
+		res.MediaSkipped = len(skipped)
+		if len(skipped) > 0 {
+			logger.Info("skipped items without audio or video enclosure", "stage", "filter", "skipped", len(skipped), "remaining", len(newItems))
+		}
+		for _, item := range skipped {
+			logger.Debug("skipped item", "stage", "filter", "item_guid", item.GUID, "title", item.Title)
+		}
+	}
+	if len(newItems) == 0 {
+		return nil
+	}
+
+	// Generate summaries if enabled
+	if w.summarizer.IsEnabled() {
+		for _, item := range newItems {
+			itemLogger := logger.With("stage", "summarize", "item_guid", item.GUID)
+			if feed.FetchFullContent {
+				w.fetchFullText(ctx, item, itemLogger)
+			}
+			summary, err := w.summarize(ctx, item, itemLogger)
+			if err != nil {
+				// 总结失败时使用原始描述
+				itemLogger.Warn("summary failed, using original description", "title", item.Title, "error", err)
+				item.Summary = ""
+				res.SummariesFailed++
+			} else {
+				item.Summary = summary
+				res.SummariesSucceeded++
+				itemLogger.Debug("generated summary", "title", item.Title, "chars", len([]rune(summary)), "summary", truncateSummary(summary, 50))
+			}
+		}
+		logger.Info("summaries generated", "stage", "summarize", "succeeded", res.SummariesSucceeded, "failed", res.SummariesFailed)
+	} else {
+		logger.Debug("AI summarizer disabled, skipping summaries", "stage", "summarize")
+	}
+
+	return newItems
+}
+
+// scheduleNextPoll 按订阅源声明的更新间隔推迟下次抓取。为了不因运行时间的
+// 小幅波动错过一轮，实际等待比声明的间隔短 pollSlack，且最长不超过 maxPollDelay。
+func (w *watcher) scheduleNextPoll(feed config.Feed, fetchedAt time.Time, interval time.Duration) {
+	if feed.IgnoreUpdateHints || interval <= 0 {
+		w.state.SetNextPoll(feed.ID, time.Time{})
+		return
+	}
+	wait := min(interval, maxPollDelay)
+	wait -= time.Duration(float64(wait) * pollSlack)
+	w.state.SetNextPoll(feed.ID, fetchedAt.Add(wait))
+}
+
+// configuredURL 返回配置中的订阅源地址或网站地址
+func configuredURL(feed config.Feed) string {
+	if feed.URL != "" {
+		return feed.URL
+	}
+	return feed.Site
+}
+
+// feedURL 返回本次要抓取的地址：优先使用自动发现的地址，
+// 其次是配置地址永久重定向到的地址，最后是配置的 url 或网站地址
+func (w *watcher) feedURL(feed config.Feed) string {
+	if u := w.state.DiscoveredURL(feed.ID); u != "" {
+		return u
+	}
+	if from, to := w.state.Moved(feed.ID); to != "" && from == configuredURL(feed) {
+		return to
+	}
+	return configuredURL(feed)
+}
+
+// recordMove 记录订阅源的永久迁移，之后的运行直接抓取新地址。
+// 自动发现的地址迁移时直接更新发现的地址。
+func (w *watcher) recordMove(feed config.Feed, from, to string, res *report.FeedResult, logger *slog.Logger) {
+	logger.Warn("feed moved permanently", "stage", "fetch", "from", from, "to", to)
+	res.MovedTo = to
+	if from == w.state.DiscoveredURL(feed.ID) {
+		w.state.SetDiscoveredURL(feed.ID, to)
+		return
+	}
+	w.state.SetMoved(feed.ID, configuredURL(feed), to)
+}
+
+// discover 从网页响应中找到订阅源，记录到状态中并抓取它
+func (w *watcher) discover(ctx context.Context, feed config.Feed, page *fetcher.Response, logger *slog.Logger) (*fetcher.Response, error) {
+	candidates, err := w.fetcher.DiscoverFromHTML(ctx, page.URL, page.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	feedURL := candidates[0].URL
+	logger.Info("discovered feed", "stage", "discover", "site", page.URL, "url", feedURL)
+
+	resp, err := w.fetcher.Fetch(ctx, feedURL, fetcher.Validators{})
+	if err != nil {
+		return nil, err
+	}
+	if fetcher.IsHTML(resp.ContentType, resp.Body) {
+		return nil, fmt.Errorf("discovered URL %s is not a feed", feedURL)
+	}
+	if resp.PermanentURL != "" {
+		feedURL = resp.PermanentURL
+	}
+
+	w.state.SetDiscoveredURL(feed.ID, feedURL)
+	return resp, nil
+}
+
+// fetchFullText 下载条目原文并提取正文，失败时保留原始描述
+func (w *watcher) fetchFullText(ctx context.Context, item *parser.Item, logger *slog.Logger) {
+	if item.Link == "" {
+		return
+	}
+	page, err := w.fetcher.Get(ctx, item.Link)
+	if err != nil {
+		logger.Warn("failed to fetch full content", "url", item.Link, "error", err)
+		return
+	}
+	text, err := extract.Text(page.Body, page.ContentType)
+	if err != nil {
+		logger.Warn("failed to extract full content", "url", item.Link, "error", err)
+		return
+	}
+	item.FullText = text
+	logger.Debug("extracted full content", "url", item.Link, "chars", len([]rune(text)))
+}
+
+// summarize 优先从缓存读取总结，未命中时调用 API 并写入缓存。
+// 依次使用提取的正文、订阅源中的全文和描述生成总结。
+func (w *watcher) summarize(ctx context.Context, item *parser.Item, logger *slog.Logger) (string, error) {
+	content := item.Description
+	if item.FullText != "" {
+		content = item.FullText
+	} else if item.Content != "" {
+		content = parser.HTMLToText(item.Content)
+	}
+
+	if w.cache == nil {
+		return w.summarizer.Summarize(ctx, item.Title, content)
+	}
+
+	key := w.summarizer.CacheKey(item.Title, content)
+	if summary, ok := w.cache.Get(key); ok {
+		logger.Debug("using cached summary")
+		return summary, nil
+	}
+
+	summary, err := w.summarizer.Summarize(ctx, item.Title, content)
+	if err != nil {
+		return "", err
+	}
+	if w.dryRun {
+		return summary, nil
+	}
+	if err := w.cache.Put(key, summary); err != nil {
+		logger.Warn("failed to cache summary", "error", err)
+	}
+	return summary, nil
+}
+
+// canceled 报告 ctx 是否已被取消或超时。此时的错误来自退出而不是订阅源，
+// 标记为中断，不清除发现的地址，也不计入健康状况
+func canceled(ctx context.Context, res *report.FeedResult, stage string, logger *slog.Logger) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	logger.Warn("interrupted", "stage", stage, "error", ctx.Err())
+	res.Status = report.StatusCanceled
+	return true
+}
+
+// mediaItems 返回带音视频附件的条目，并把它们标记为媒体条目，
+// 以及没有附件而被跳过的条目
+func mediaItems(items []*parser.Item) (kept, skipped []*parser.Item) {
+	kept = make([]*parser.Item, 0, len(items))
+	for _, item := range items {
+		if item.MediaEnclosure() == nil {
+			skipped = append(skipped, item)
+			continue
+		}
+		item.Media = true
+		kept = append(kept, item)
+	}
+	return kept, skipped
+}
+
+// downloadMedia 把新条目的附件加入下载队列并逐个下载。
+// 失败或被退出信号中断的附件留在队列中，下次运行时从中断处续传；超过大小限制的直接移出队列。
+func (w *watcher) downloadMedia(ctx context.Context, feed config.Feed, newItems []*parser.Item, res *report.FeedResult, logger *slog.Logger) {
+	logger = logger.With("stage", "download")
+
+	var encs []parser.Enclosure
+	for _, item := range newItems {
+		if enc := item.MediaEnclosure(); enc != nil {
+			encs = append(encs, *enc)
+		}
+	}
+	if len(encs) > 0 {
+		w.state.AddDownloads(feed.ID, encs)
+	}
+
+	// 调度器传入的 ctx 不会被取消，退出信号通过 w.shutdown 中断下载
+	if w.shutdown != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(w.shutdown, cancel)()
+	}
+
+	d := media.New(filepath.Join(feed.MediaDownloadDir, feed.ID), int64(feed.MediaMaxSizeMB)<<20)
+	remaining := d.DownloadAll(ctx, w.state.Downloads(feed.ID), func(enc parser.Enclosure, path string, err error) {
+		switch {
+		case err == nil:
+			logger.Info("downloaded enclosure", "url", enc.URL, "path", path)
+			w.state.RemoveDownload(feed.ID, enc.URL)
+			res.MediaDownloaded++
+		case errors.Is(err, media.ErrTooLarge):
+			logger.Warn("skipped enclosure", "url", enc.URL, "error", err)
+			w.state.RemoveDownload(feed.ID, enc.URL)
+		case ctx.Err() != nil:
+			logger.Info("download interrupted by shutdown, will resume", "url", enc.URL)
+		default:
+			logger.Warn("failed to download enclosure, will retry", "url", enc.URL, "error", err)
+			res.MediaDownloadFailed++
+		}
+	})
+	if remaining > 0 {
+		logger.Info("shutting down, downloads left in queue", "remaining", remaining)
+	}
+}
+
+// deliver 发送通知。聚合订阅源的条目先写入持久化的缓冲区，
+// 窗口到期后再作为一条汇总通知发送，因此跨多次运行也能生效。
+// 统计中单条通知按条目计数，汇总通知计为一条。
+func (w *watcher) deliver(feed config.Feed, newItems []*parser.Item, res *report.FeedResult, logger *slog.Logger) {
+	logger = logger.With("stage", "notify")
+	notifier := w.notifiers[feed.ID]
+
+	if !feed.Aggregate {
+		if len(newItems) == 0 {
+			return
+		}
+		if err := notifier.Notify(feed.Name, newItems); err != nil {
+			logger.Error("failed to send notifications", "items", len(newItems), "error", err)
+			res.Fail("notify", err)
+			res.NotificationsFailed += len(newItems)
+			w.archiveItems(feed, newItems, archive.Failed, logger)
+		} else {
+			logger.Info("sent notifications", "items", len(newItems))
+			res.NotificationsSent += len(newItems)
+			w.archiveItems(feed, newItems, archive.Sent, logger)
+		}
+		return
+	}
+
+	window := time.Duration(feed.AggregateWindowMinutes) * time.Minute
+	if window <= 0 {
+		if len(newItems) == 0 {
+			return
+		}
+		if err := notifier.NotifyAggregate(feed.Name, newItems); err != nil {
+			logger.Error("failed to send aggregate notification", "items", len(newItems), "error", err)
+			res.Fail("notify", err)
+			res.NotificationsFailed++
+			w.archiveItems(feed, newItems, archive.Failed, logger)
+		} else {
+			logger.Info("sent aggregate notification", "items", len(newItems))
+			res.NotificationsSent++
+			w.archiveItems(feed, newItems, archive.Sent, logger)
+		}
+		return
+	}
+
+	now := time.Now()
+	if len(newItems) > 0 {
+		w.state.AddPending(feed.ID, newItems, now)
+		w.archiveItems(feed, newItems, archive.Pending, logger)
+	}
+
+	pending, since := w.state.Pending(feed.ID)
+	if len(pending) == 0 {
+		return
+	}
+
+	if flushAt := since.Add(window); now.Before(flushAt) {
+		logger.Info("buffered items for digest", "pending", len(pending), "due", flushAt.Format(time.RFC3339))
+		return
+	}
+
+	// 发送失败时保留缓冲区，下次运行重试
+	if err := notifier.NotifyAggregate(feed.Name, pending); err != nil {
+		logger.Error("failed to send aggregate notification", "items", len(pending), "error", err)
+		res.Fail("notify", err)
+		res.NotificationsFailed++
+		return
+	}
+	w.state.ClearPending(feed.ID)
+	res.NotificationsSent++
+	logger.Info("sent aggregate notification", "items", len(pending))
+	w.archiveItems(feed, pending, archive.Sent, logger)
+}
+
+// archiveItems 以 notified 状态归档条目，归档失败不影响通知
+func (w *watcher) archiveItems(feed config.Feed, items []*parser.Item, notified string, logger *slog.Logger) {
+	if w.archive == nil {
+		return
+	}
+	if err := w.archive.Add(feed.ID, feed.DedupeKey, items, notified, time.Now()); err != nil {
+		logger.Warn("failed to archive items", "items", len(items), "error", err)
+	}
+}
+
+// buildChannels 根据配置创建通知渠道。未配置任何渠道时，
+// 使用基于环境变量的 Bark 渠道以保持向后兼容。
+func buildChannels(cfgs []config.Channel) (notifier.Multi, error) {
+	if len(cfgs) == 0 {
+		return notifier.Multi{{Name: "bark", Kind: "bark", Notifier: notifier.NewBark()}}, nil
+	}
+
+	channels := make(notifier.Multi, 0, len(cfgs))
+	for _, c := range cfgs {
+		opts := make(map[string]string, len(c.Options))
+		for k, v := range c.Options {
+			opts[k] = os.ExpandEnv(v)
+		}
+		n, err := notifier.New(c.Type, opts)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", c.Name, err)
+		}
+		channels = append(channels, notifier.Channel{Name: c.Name, Kind: c.Type, Notifier: n})
+	}
+	return channels, nil
+}
+
+// feedNotifier 返回订阅源选择的渠道，未指定时使用全部渠道
+func feedNotifier(feed config.Feed, channels notifier.Multi) (notifier.Notifier, error) {
+	if len(feed.Channels) == 0 {
+		return channels, nil
+	}
+
+	selected := make(notifier.Multi, 0, len(feed.Channels))
+	for _, name := range feed.Channels {
+		found := false
+		for _, ch := range channels {
+			if ch.Name == name {
+				selected = append(selected, ch)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// truncateSummary 截断摘要用于日志显示
+func truncateSummary(s string, maxLen int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "..."
+}
@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/rsswatcher/rsswatcher/internal/deduper"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+// 条目的通知状态
+const (
+	Sent    = "sent"    // 已发送
+	Failed  = "failed"  // 发送失败
+	Pending = "pending" // 在聚合窗口中等待发送
+	Off     = "off"     // 订阅源关闭了通知
+)
+
+// timeFormat 是数据库中的时间格式，定长以便按字符串比较
+const timeFormat = "2006-01-02T15:04:05Z"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS items (
+	feed_id     TEXT NOT NULL,
+	item_key    TEXT NOT NULL,
+	title       TEXT NOT NULL,
+	link        TEXT NOT NULL,
+	description TEXT NOT NULL,
+	summary     TEXT NOT NULL,
+	published   TEXT NOT NULL,
+	notified    TEXT NOT NULL,
+	archived_at TEXT NOT NULL,
+	PRIMARY KEY (feed_id, item_key)
+);
+CREATE INDEX IF NOT EXISTS items_published ON items (published);
+`
+
+// ftsSchema 是标题、描述和总结的全文索引，由触发器随 items 表同步。
+// trigram 分词按字符切分，不依赖空格，中文也能搜索
+const ftsSchema = `
+CREATE VIRTUAL TABLE items_fts USING fts5(
+	title, description, summary,
+	content = 'items', content_rowid = 'rowid', tokenize = 'trigram'
+);
+CREATE TRIGGER items_fts_insert AFTER INSERT ON items BEGIN
+	INSERT INTO items_fts (rowid, title, description, summary)
+	VALUES (new.rowid, new.title, new.description, new.summary);
+END;
+CREATE TRIGGER items_fts_delete AFTER DELETE ON items BEGIN
+	INSERT INTO items_fts (items_fts, rowid, title, description, summary)
+	VALUES ('delete', old.rowid, old.title, old.description, old.summary);
+END;
+CREATE TRIGGER items_fts_update AFTER UPDATE ON items BEGIN
+	INSERT INTO items_fts (items_fts, rowid, title, description, summary)
+	VALUES ('delete', old.rowid, old.title, old.description, old.summary);
+	INSERT INTO items_fts (rowid, title, description, summary)
+	VALUES (new.rowid, new.title, new.description, new.summary);
+END;
+-- 为创建索引之前归档的条目建立索引
+INSERT INTO items_fts (items_fts) VALUES ('rebuild');
+`
+
+// minTermLen 是 trigram 索引能匹配的最短搜索词长度（字符数）
+const minTermLen = 3
+
+// Archive 是保存已处理条目的本地 SQLite 数据库，可被多个进程同时使用
+type Archive struct {
+	db *sql.DB
+}
+
+// Entry 是归档中的一个条目
+type Entry struct {
+	FeedID      string
+	Title       string
+	Link        string
+	Description string
+	Summary     string
+	// Published 是条目的发布时间，订阅源未提供时为归档时间
+	Published  time.Time
+	Notified   string
+	ArchivedAt time.Time
+}
+
+// Query 是搜索条件，零值字段不参与过滤
+type Query struct {
+	// Text 中以空白分隔的每个词都必须出现在标题、总结或描述中，不区分 ASCII 大小写。
+	// 为空时只按其他条件过滤
+	Text   string
+	FeedID string
+	Since  time.Time
+	Until  time.Time // 不含
+	Limit  int
+}
+
+// Open 打开 path 处的归档，不存在时创建
+func Open(path string) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Archive{db: db}, nil
+}
+
+// migrate 创建表和全文索引，已存在时跳过
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+	var n int
+	if err := tx.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'items_fts'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := tx.Exec(ftsSchema); err != nil {
+			return fmt.Errorf("full-text index: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// Add 以 notified 状态归档 feedID 的条目，dedupeKey 为订阅源配置的 dedupe_key，
+// 与去重使用相同的 key 识别同一条目。已归档的条目更新内容和通知状态，
+// 归档时间保持不变；本次条目没有发布时间时保留已保存的发布时间。
+func (a *Archive) Add(feedID, dedupeKey string, items []*parser.Item, notified string, at time.Time) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO items
+		(feed_id, item_key, title, link, description, summary, published, notified, archived_at)
+		VALUES (?, ?, ?, ?, ?, ?, COALESCE(?, ?), ?, ?)
+		ON CONFLICT (feed_id, item_key) DO UPDATE SET
+			title = excluded.title, link = excluded.link, description = excluded.description,
+			summary = excluded.summary, published = COALESCE(?, items.published), notified = excluded.notified`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range items {
+		// 没有发布时间时为 NULL：新条目以归档时间代替，已归档的条目保留原值
+		var published any
+		if !item.Published.IsZero() {
+			published = formatTime(item.Published)
+		}
+		if _, err := stmt.Exec(feedID, deduper.ItemKey(item, dedupeKey), item.Title, item.Link, item.Description, item.Summary,
+			published, formatTime(at), notified, formatTime(at), published); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Search 返回符合条件的条目。有搜索词时按相关度排列，否则按发布时间从新到旧排列。
+// 少于 minTermLen 个字符的词无法使用全文索引，逐条比较标题、总结和描述
+func (a *Archive) Search(q Query) ([]Entry, error) {
+	var terms []string
+	var where []string
+	var args []any
+	for _, word := range strings.Fields(q.Text) {
+		if utf8.RuneCountInString(word) >= minTermLen {
+			terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+			continue
+		}
+		where = append(where, `(i.title LIKE ? ESCAPE '\' OR i.summary LIKE ? ESCAPE '\' OR i.description LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(word) + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	if len(terms) > 0 {
+		where = append(where, "items_fts MATCH ?")
+		args = append(args, strings.Join(terms, " "))
+	}
+	if q.FeedID != "" {
+		where = append(where, "i.feed_id = ?")
+		args = append(args, q.FeedID)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "i.published >= ?")
+		args = append(args, formatTime(q.Since))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "i.published < ?")
+		args = append(args, formatTime(q.Until))
+	}
+
+	query := `SELECT i.feed_id, i.title, i.link, i.description, i.summary, i.published, i.notified, i.archived_at FROM items i`
+	if len(terms) > 0 {
+		query += " JOIN items_fts ON items_fts.rowid = i.rowid"
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY "
+	if len(terms) > 0 {
+		query += "items_fts.rank, "
+	}
+	query += "i.published DESC, i.archived_at DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var published, archivedAt string
+		if err := rows.Scan(&e.FeedID, &e.Title, &e.Link, &e.Description, &e.Summary, &published, &e.Notified, &archivedAt); err != nil {
+			return nil, err
+		}
+		if e.Published, err = time.Parse(timeFormat, published); err != nil {
+			return nil, err
+		}
+		if e.ArchivedAt, err = time.Parse(timeFormat, archivedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// key 返回条目在订阅源内的唯一标识
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+func openTest(t *testing.T) *Archive {
+	t.Helper()
+	a, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func titles(entries []Entry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Title
+	}
+	return out
+}
+
+func TestArchive_AddSearch(t *testing.T) {
+	a := openTest(t)
+	day := func(d int) time.Time { return time.Date(2026, 9, d, 8, 0, 0, 0, time.UTC) }
+	now := day(30)
+
+	if err := a.Add("blog", "", []*parser.Item{
+		{GUID: "1", Title: "Go 1.23 released", Link: "https://example.com/1", Published: day(1)},
+		{GUID: "2", Title: "Weekly notes", Summary: "Maintenance notice for the office network", Published: day(10)},
+	}, Sent, now); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := a.Add("school", "", []*parser.Item{
+		{GUID: "a", Title: "停课通知", Description: "因台风停课一天", Published: day(20)},
+		{Link: "https://example.com/b", Title: "Network notice"},
+	}, Pending, now); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"all", Query{}, []string{"Network notice", "停课通知", "Weekly notes", "Go 1.23 released"}},
+		{"summary", Query{Text: "NETWORK notice"}, []string{"Network notice", "Weekly notes"}},
+		{"cjk", Query{Text: "因台风"}, []string{"停课通知"}},
+		// 少于三个字符的词不走全文索引
+		{"short", Query{Text: "台风 go"}, nil},
+		{"short title", Query{Text: "Go released"}, []string{"Go 1.23 released"}},
+		{"feed", Query{Text: "notice", FeedID: "blog"}, []string{"Weekly notes"}},
+		{"since", Query{Since: day(10), Until: day(21)}, []string{"停课通知", "Weekly notes"}},
+		{"limit", Query{Limit: 1}, []string{"Network notice"}},
+		{"wildcard", Query{Text: "%"}, nil},
+		{"quote", Query{Text: `"notice`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Search(tt.q)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if g := titles(got); strings.Join(g, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Search() = %q, want %q", g, tt.want)
+			}
+		})
+	}
+
+	// 没有发布时间的条目按归档时间计
+	got, _ := a.Search(Query{FeedID: "school", Text: "network"})
+	if len(got) != 1 || !got[0].Published.Equal(now) || got[0].Notified != Pending {
+		t.Errorf("entry = %+v", got)
+	}
+}
+
+func TestArchive_UpdateStatus(t *testing.T) {
+	a := openTest(t)
+	first := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	item := &parser.Item{GUID: "1", Title: "Digest item"}
+
+	if err := a.Add("blog", "", []*parser.Item{item}, Pending, first); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	item.Summary = "summary"
+	if err := a.Add("blog", "", []*parser.Item{item}, Sent, first.Add(time.Hour)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := a.Search(Query{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Search() returned %d entries, want 1", len(got))
+	}
+	// 条目没有发布时间，更新时保留首次归档时记录的时间
+	if e := got[0]; e.Notified != Sent || e.Summary != "summary" || !e.ArchivedAt.Equal(first) || !e.Published.Equal(first) {
+		t.Errorf("entry = %+v", e)
+	}
+	// 全文索引随更新同步
+	if got, _ := a.Search(Query{Text: "summary"}); len(got) != 1 {
+		t.Errorf("Search(summary) returned %d entries, want 1", len(got))
+	}
+}
+
+func TestArchive_DedupeKey(t *testing.T) {
+	a := openTest(t)
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// 订阅源每次生成新的 GUID，按 dedupe_key: link 仍是同一条目
+	for i, guid := range []string{"a1", "b2"} {
+		item := &parser.Item{GUID: guid, Title: "Weekly notice", Link: "https://example.com/notice"}
+		status := Pending
+		if i > 0 {
+			status = Sent
+		}
+		if err := a.Add("school", "link", []*parser.Item{item}, status, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	got, err := a.Search(Query{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Notified != Sent {
+		t.Errorf("Search() = %+v, want one sent entry", got)
+	}
+}
+
+func TestArchive_Rank(t *testing.T) {
+	a := openTest(t)
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if err := a.Add("blog", "", []*parser.Item{
+		{GUID: "1", Title: "Release notes", Description: "A long post that mentions a typhoon once among many other unrelated words", Published: now},
+		{GUID: "2", Title: "Typhoon warning", Summary: "Typhoon warning for the coast", Published: now.Add(-48 * time.Hour)},
+	}, Sent, now); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// 匹配更多的条目排在前面，即使发布得更早
+	got, err := a.Search(Query{Text: "typhoon"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if g := titles(got); strings.Join(g, "|") != "Typhoon warning|Release notes" {
+		t.Errorf("Search() = %q", g)
+	}
+}
+
+func TestOpen_IndexesExistingItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 创建全文索引之前的归档
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO items VALUES ('blog', '1', 'Old entry', '', 'archived before the index', '', '2026-01-01T00:00:00Z', 'sent', '2026-01-01T00:00:00Z')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer a.Close()
+	got, err := a.Search(Query{Text: "before index"})
+	if err != nil || len(got) != 1 {
+		t.Errorf("Search() = %+v, %v", got, err)
+	}
+}
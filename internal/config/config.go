@@ -1,35 +1,358 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Feeds []Feed `yaml:"feeds"`
+	Channels []Channel `yaml:"channels,omitempty"`
+	Output   Output    `yaml:"output,omitempty"`
+	Feeds    []Feed    `yaml:"feeds"`
+}
+
+// Output 是合并所有订阅源新条目的输出订阅源，Path 为空时不输出
+type Output struct {
+	Path     string `yaml:"path"`
+	Format   string `yaml:"format,omitempty"` // atom 或 json
+	Title    string `yaml:"title,omitempty"`
+	Link     string `yaml:"link,omitempty"` // 输出订阅源自身的公开地址
+	MaxItems int    `yaml:"max_items,omitempty"`
+	Order    string `yaml:"order,omitempty"` // published 或 added
+}
+
+// Channel 是一个命名的通知渠道，Type 对应 notifier 中注册的后端类型，
+// 其余字段作为后端配置项原样传入，值中的 ${VAR} 在运行时展开为环境变量
+type Channel struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"`
+	Options map[string]string `yaml:",inline"`
 }
 
 type Feed struct {
-	ID                     string `yaml:"id"`
-	Name                   string `yaml:"name"`
-	URL                    string `yaml:"url"`
-	Notify                 bool   `yaml:"notify"`
-	DedupeKey              string `yaml:"dedupe_key"`
-	Aggregate              bool   `yaml:"aggregate"`
-	AggregateWindowMinutes int    `yaml:"aggregate_window_minutes"`
+	ID                     string   `yaml:"id"`
+	Name                   string   `yaml:"name"`
+	URL                    string   `yaml:"url,omitempty"`
+	Site                   string   `yaml:"site,omitempty"` // 网站地址，运行时自动发现订阅源
+	Notify                 bool     `yaml:"notify"`
+	DedupeKey              string   `yaml:"dedupe_key,omitempty"`
+	Aggregate              bool     `yaml:"aggregate,omitempty"`
+	AggregateWindowMinutes int      `yaml:"aggregate_window_minutes,omitempty"`
+	SeenRetention          int      `yaml:"seen_retention,omitempty"`
+	Channels               []string `yaml:"channels,omitempty"`
+	Interval               Duration `yaml:"interval,omitempty"`
+	Filters                Filters  `yaml:"filters,omitempty"`
+	Category               string   `yaml:"category,omitempty"`             // 以 "/" 分隔的分类路径，用于 OPML 导入导出；分类名中的 "/" 写作 "\/"
+	FetchFullContent       bool     `yaml:"fetch_full_content,omitempty"`   // 下载原文提取正文用于总结
+	Media                  bool     `yaml:"media,omitempty"`                // 只关注带音视频附件的条目
+	MediaDownloadDir       string   `yaml:"media_download_dir,omitempty"`   // 非空时把附件下载到该目录下的 <id> 子目录
+	MediaMaxSizeMB         int      `yaml:"media_max_size_mb,omitempty"`    // 单个附件的大小上限，0 表示不限制
+	IgnoreUpdateHints      bool     `yaml:"ignore_update_hints,omitempty"`  // 忽略订阅源声明的 <ttl> 和 sy:updatePeriod
+	AlertAfterFailures     int      `yaml:"alert_after_failures,omitempty"` // 连续失败多少次后告警，默认 3，0 表示不告警
+	StaleAfter             Duration `yaml:"stale_after,omitempty"`          // 超过该时间没有新条目时告警，0 表示不检查
+}
+
+// Filters 决定哪些新条目需要总结和通知。配置了 Include 时，
+// 条目必须匹配至少一条 Include 规则；匹配任一 Exclude 规则的条目被过滤。
+type Filters struct {
+	Include []FilterRule `yaml:"include,omitempty"`
+	Exclude []FilterRule `yaml:"exclude,omitempty"`
+}
+
+// FilterRule 是一条关键字（不区分大小写）或正则匹配规则，
+// Fields 为匹配的字段，默认为 title 和 description
+type FilterRule struct {
+	Keyword string   `yaml:"keyword,omitempty"`
+	Regex   string   `yaml:"regex,omitempty"`
+	Fields  []string `yaml:"fields,omitempty"`
+}
+
+// FilterFields 是过滤规则可以匹配的字段
+var FilterFields = []string{"title", "description", "link", "author", "category"}
+
+// ChannelTypes 是支持的通知渠道类型，与 notifier 中注册的后端一致
+var ChannelTypes = []string{"bark", "discord", "email", "gotify", "ntfy", "slack", "telegram", "webhook"}
+
+// Duration 是以 "30m"、"1h30m" 等字符串表示的时间间隔
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		// 返回 TypeError 让解码器继续处理其余字段
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: invalid duration %q", value.Line, s)}}
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Load 读取并校验配置文件，校验失败时返回 Errors
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	return Parse(data)
+}
+
+// Parse 解析并校验配置，报告所有问题及其所在行列，
+// 校验通过后填入默认值。
+func Parse(data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, err
 	}
 
+	var cfg Config
+	if len(root.Content) == 0 {
+		return &cfg, nil
+	}
+	doc := root.Content[0]
+
+	v := newValidator()
+	v.walk(doc, reflect.TypeOf(cfg), "")
+	// 类型错误已在 walk 中报告；解码会跳过出错的字段，
+	// 其余字段仍可用于语义检查，从而一次报告所有问题
+	if err := doc.Decode(&cfg); err != nil {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return nil, err
+		}
+	}
+	v.check(&cfg)
+	if len(v.errs) > 0 {
+		v.errs.sort()
+		return nil, v.errs
+	}
+
+	v.applyDefaults(&cfg)
 	return &cfg, nil
 }
+
+// AppendFeeds 把 feeds 追加到 path 指向的配置文件末尾，原有内容保持不变。
+// feeds 是文件中最后一个顶层块序列时直接以文本追加，以保留空行等格式；
+// 否则修改 YAML 节点树后重新写出，注释仍会保留。
+func AppendFeeds(path string, feeds []Feed) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if len(root.Content) == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level must be a mapping", path)
+	}
+
+	var seq *yaml.Node
+	last := false
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "feeds" {
+			seq = doc.Content[i+1]
+			last = i+2 == len(doc.Content)
+			break
+		}
+	}
+
+	if last && seq.Kind == yaml.SequenceNode && seq.Style&yaml.FlowStyle == 0 && len(seq.Content) > 0 {
+		// 条目内容的列号减去 "- " 即为序列的缩进
+		indent := seq.Content[0].Column - 3
+		return appendText(path, data, feeds, indent)
+	}
+
+	if seq == nil {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "feeds"}, seq)
+	} else if seq.Kind != yaml.SequenceNode {
+		// "feeds:" 为空时是 null 标量
+		*seq = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	for _, feed := range feeds {
+		var node yaml.Node
+		if err := node.Encode(feed); err != nil {
+			return err
+		}
+		seq.Content = append(seq.Content, &node)
+	}
+
+	return writeYAML(path, &root)
+}
+
+// URLChange 把 ID 为 FeedID 的订阅源的 url 或 site 从 From 改为 To
+type URLChange struct {
+	FeedID string
+	From   string
+	To     string
+}
+
+// UpdateFeedURLs 在 path 指向的配置文件中原地替换订阅源地址，文件的其余内容
+// 和格式保持不变。当前值不等于 From 的订阅源会被跳过，返回实际修改的数量。
+func UpdateFeedURLs(path string, changes []URLChange) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return 0, err
+	}
+	if len(root.Content) == 0 {
+		return 0, nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	updated := 0
+	for _, c := range changes {
+		node := feedURLNode(root.Content[0], c.FeedID, c.From)
+		if node == nil {
+			continue
+		}
+		line, ok := replaceScalar(lines[node.Line-1], node, c.To)
+		if !ok {
+			return updated, fmt.Errorf("%s:%d: cannot rewrite url of feed %q", path, node.Line, c.FeedID)
+		}
+		lines[node.Line-1] = line
+		updated++
+	}
+	if updated == 0 {
+		return 0, nil
+	}
+
+	return updated, writeFile(path, []byte(strings.Join(lines, "")))
+}
+
+// feedURLNode 返回 ID 为 id 的订阅源中值等于 from 的 url 或 site 节点
+func feedURLNode(doc *yaml.Node, id, from string) *yaml.Node {
+	feeds := mappingValue(doc, "feeds")
+	if feeds == nil || feeds.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, feed := range feeds.Content {
+		if idNode := mappingValue(feed, "id"); idNode == nil || idNode.Value != id {
+			continue
+		}
+		for _, key := range []string{"url", "site"} {
+			if n := mappingValue(feed, key); n != nil && n.Kind == yaml.ScalarNode && n.Value == from {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// replaceScalar 把 line 中 node 所在位置的单行标量替换为 value，保留原有的引号风格
+func replaceScalar(line string, node *yaml.Node, value string) (string, bool) {
+	start := node.Column - 1
+	if start < 0 || start >= len(line) {
+		return "", false
+	}
+	rest := line[start:]
+
+	var n int
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		end := strings.IndexByte(rest[1:], '"')
+		if rest[0] != '"' || end < 0 || rest[1:end+1] != node.Value {
+			return "", false
+		}
+		n = end + 2
+		value = `"` + value + `"`
+	case yaml.SingleQuotedStyle:
+		end := strings.IndexByte(rest[1:], '\'')
+		if rest[0] != '\'' || end < 0 || rest[1:end+1] != node.Value {
+			return "", false
+		}
+		n = end + 2
+		value = "'" + value + "'"
+	case 0:
+		if !strings.HasPrefix(rest, node.Value) {
+			return "", false
+		}
+		n = len(node.Value)
+	default:
+		return "", false
+	}
+	return line[:start] + value + rest[n:], true
+}
+
+func appendText(path string, data []byte, feeds []Feed, indent int) error {
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimRight(data, " \t\r\n"))
+	buf.WriteString("\n")
+
+	prefix := strings.Repeat(" ", indent)
+	for _, feed := range feeds {
+		var item bytes.Buffer
+		enc := yaml.NewEncoder(&item)
+		enc.SetIndent(2)
+		if err := enc.Encode([]Feed{feed}); err != nil {
+			return err
+		}
+		enc.Close()
+
+		buf.WriteString("\n")
+		for _, line := range strings.Split(strings.TrimRight(item.String(), "\n"), "\n") {
+			buf.WriteString(prefix + line + "\n")
+		}
+	}
+
+	return writeFile(path, buf.Bytes())
+}
+
+func writeYAML(path string, root *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return writeFile(path, buf.Bytes())
+}
+
+func writeFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
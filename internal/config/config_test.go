@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfig_Load(t *testing.T) {
@@ -77,3 +79,301 @@ func TestConfig_LoadNonExistent(t *testing.T) {
 		t.Error("Load() expected error, got nil")
 	}
 }
+
+func TestConfig_LoadInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "interval.yaml")
+
+	configYAML := `feeds:
+  - id: fast
+    name: Fast
+    url: https://example.com/rss
+    interval: 15m
+`
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := time.Duration(cfg.Feeds[0].Interval); got != 15*time.Minute {
+		t.Errorf("Interval = %v, want 15m", got)
+	}
+
+	if err := os.WriteFile(configPath, []byte(strings.Replace(configYAML, "15m", "soon", 1)), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(configPath); err == nil {
+		t.Error("Load() with invalid interval: expected error")
+	}
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg, err := Parse([]byte(`feeds:
+  - id: a
+    name: A
+    url: https://example.com/a
+    aggregate: true
+  - id: b
+    name: B
+    url: https://example.com/b
+    notify: false
+    alert_after_failures: 0
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	a, b := cfg.Feeds[0], cfg.Feeds[1]
+	if !a.Notify {
+		t.Error("a.Notify = false, want default true")
+	}
+	if a.DedupeKey != "guid" {
+		t.Errorf("a.DedupeKey = %q, want guid", a.DedupeKey)
+	}
+	if a.AggregateWindowMinutes != 30 {
+		t.Errorf("a.AggregateWindowMinutes = %d, want 30", a.AggregateWindowMinutes)
+	}
+	if a.AlertAfterFailures != 3 {
+		t.Errorf("a.AlertAfterFailures = %d, want 3", a.AlertAfterFailures)
+	}
+	if b.Notify {
+		t.Error("b.Notify = true, want false")
+	}
+	if b.AlertAfterFailures != 0 {
+		t.Errorf("b.AlertAfterFailures = %d, want explicit 0", b.AlertAfterFailures)
+	}
+}
+
+func TestConfig_ValidationErrors(t *testing.T) {
+	configYAML := `feeds:
+  - id: dup
+    name: First
+    url: https://example.com/a
+    notfy: true
+  - id: dup
+    name: Second
+    url: ""
+    dedupe_key: uuid
+    interval: soon
+  - id: c
+    name: C
+    url: ftp://example.com/c
+    notify: maybe
+    channels: [nowhere]
+`
+	_, err := Parse([]byte(configYAML))
+	if err == nil {
+		t.Fatal("Parse() expected error")
+	}
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Parse() error type = %T, want Errors", err)
+	}
+
+	want := []string{
+		"5:5: feeds[0].notfy: unknown field",
+		`6:9: feeds[1].id: duplicate feed id "dup"`,
+		"8:10: feeds[1].url: one of url or site is required",
+		`9:17: feeds[1].dedupe_key: unknown dedupe_key "uuid"`,
+		`10:15: feeds[1].interval: invalid duration "soon"`,
+		"13:10: feeds[2].url: invalid url",
+		"14:13: feeds[2].notify: cannot unmarshal",
+		`15:16: feeds[2].channels[0]: unknown channel "nowhere"`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d = %q, want prefix %q", i, errs[i].Error(), w)
+		}
+	}
+}
+
+func TestConfig_FilterValidation(t *testing.T) {
+	_, err := Parse([]byte(`feeds:
+  - id: cnu
+    name: CNU
+    url: https://example.com/cnu
+    filters:
+      include:
+        - keyword: 讲座
+          fields: [title, body]
+      exclude:
+        - regex: "("
+        - keyword: a
+          regex: b
+`))
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want Errors", err)
+	}
+
+	want := []string{
+		`8:27: feeds[0].filters.include[0].fields[1]: unknown field "body"`,
+		"10:18: feeds[0].filters.exclude[0].regex: invalid regex",
+		"11:11: feeds[0].filters.exclude[1]: keyword and regex are mutually exclusive",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d = %q, want prefix %q", i, errs[i].Error(), w)
+		}
+	}
+}
+
+func TestConfig_Output(t *testing.T) {
+	cfg, err := Parse([]byte(`output:
+  path: public/feed.xml
+feeds: []
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := Output{Path: "public/feed.xml", Format: "atom", Title: "RSS Watcher", MaxItems: 50, Order: "published"}
+	if cfg.Output != want {
+		t.Errorf("Output = %+v, want %+v", cfg.Output, want)
+	}
+
+	_, err = Parse([]byte(`output:
+  format: rss
+  order: random
+  max_items: -1
+  link: feed.xml
+feeds: []
+`))
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want Errors", err)
+	}
+
+	wantErrs := []string{
+		"2:3: output.path: path is required",
+		`2:11: output.format: unknown format "rss"`,
+		`3:10: output.order: unknown order "random"`,
+		"4:14: output.max_items: must not be negative",
+		"5:9: output.link: invalid url",
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(wantErrs), err)
+	}
+	for i, w := range wantErrs {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d = %q, want prefix %q", i, errs[i].Error(), w)
+		}
+	}
+}
+
+func TestConfig_AppendFeeds(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "feeds.yaml")
+
+	original := `# my feeds
+feeds:
+  - id: a
+    name: A
+    url: https://example.com/a
+    notify: false
+
+  - id: b
+    name: B
+    url: https://example.com/b
+`
+	if err := os.WriteFile(configPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	if err := AppendFeeds(configPath, []Feed{{ID: "c", Name: "C", URL: "https://example.com/c", Notify: true, Category: "Tech"}}); err != nil {
+		t.Fatalf("AppendFeeds() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if !strings.HasPrefix(string(data), original) {
+		t.Errorf("existing content changed:\n%s", data)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Feeds) != 3 || cfg.Feeds[0].Notify || cfg.Feeds[2].ID != "c" || cfg.Feeds[2].Category != "Tech" {
+		t.Errorf("feeds after append = %+v", cfg.Feeds)
+	}
+
+	// feeds 不是最后一个顶层键时改写节点树
+	if err := os.WriteFile(configPath, []byte("feeds:\n  - id: a\n    name: A\n    url: https://example.com/a\nchannels:\n  - name: hook\n    type: webhook\n    url: https://example.com/hook\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := AppendFeeds(configPath, []Feed{{ID: "c", Name: "C", URL: "https://example.com/c", Notify: true}}); err != nil {
+		t.Fatalf("AppendFeeds() error = %v", err)
+	}
+	cfg, err = Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Feeds) != 2 || len(cfg.Channels) != 1 {
+		t.Errorf("config after append = %+v", cfg)
+	}
+}
+
+func TestConfig_UpdateFeedURLs(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "feeds.yaml")
+	original := `# my feeds
+feeds:
+  - id: a
+    name: A
+    url: http://example.com/a   # old address
+
+  - id: b
+    name: B
+    url: "http://example.com/b"
+  - id: c
+    name: C
+    site: 'http://example.com/'
+`
+	if err := os.WriteFile(configPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	n, err := UpdateFeedURLs(configPath, []URLChange{
+		{FeedID: "a", From: "http://example.com/a", To: "https://example.com/a.xml"},
+		{FeedID: "b", From: "http://example.com/b", To: "https://example.com/b.xml"},
+		{FeedID: "c", From: "http://example.com/", To: "https://example.com/"},
+		// 当前值已不同时跳过
+		{FeedID: "a", From: "http://example.com/other", To: "https://example.com/other"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateFeedURLs() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("UpdateFeedURLs() = %d, want 3", n)
+	}
+
+	want := `# my feeds
+feeds:
+  - id: a
+    name: A
+    url: https://example.com/a.xml   # old address
+
+  - id: b
+    name: B
+    url: "https://example.com/b.xml"
+  - id: c
+    name: C
+    site: 'https://example.com/'
+`
+	data, _ := os.ReadFile(configPath)
+	if string(data) != want {
+		t.Errorf("config after update =\n%s\nwant\n%s", data, want)
+	}
+
+	if n, err := UpdateFeedURLs(configPath, []URLChange{{FeedID: "a", From: "http://example.com/a", To: "x"}}); err != nil || n != 0 {
+		t.Errorf("second UpdateFeedURLs() = %d, %v, want 0, nil", n, err)
+	}
+}
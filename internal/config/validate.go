@@ -0,0 +1,415 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultDedupeKey              = "guid"
+	defaultAggregateWindowMinutes = 30
+	defaultAlertAfterFailures     = 3
+	defaultOutputFormat           = "atom"
+	defaultOutputTitle            = "RSS Watcher"
+	defaultOutputMaxItems         = 50
+	defaultOutputOrder            = "published"
+)
+
+var (
+	dedupeKeys    = []string{"guid", "link", "title"}
+	outputFormats = []string{"atom", "json"}
+	outputOrders  = []string{"published", "added"}
+)
+
+// Error 是一个带 YAML 位置信息的配置错误
+type Error struct {
+	Line   int
+	Column int
+	Path   string
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Msg)
+}
+
+// Errors 汇总配置中的全部错误，按出现位置排序
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// validator 遍历 YAML 节点树检查结构，并记录每个路径对应的节点，
+// 供后续的语义检查定位错误。
+type validator struct {
+	nodes map[string]*yaml.Node
+	errs  Errors
+}
+
+func newValidator() *validator {
+	return &validator{nodes: make(map[string]*yaml.Node)}
+}
+
+func (v *validator) add(node *yaml.Node, path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Path:   path,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// errorf 在 path 对应的节点处报告错误；路径不存在时（例如缺少字段）
+// 逐级回退到最近的父节点。已有结构错误的路径不再重复报告。
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	for _, e := range v.errs {
+		if e.Path == path {
+			return
+		}
+	}
+	for p := path; ; {
+		if node, ok := v.nodes[p]; ok {
+			v.add(node, path, format, args...)
+			return
+		}
+		i := strings.LastIndexAny(p, ".[")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	v.errs = append(v.errs, &Error{Line: 1, Column: 1, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) has(path string) bool {
+	_, ok := v.nodes[path]
+	return ok
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// walk 按目标类型 t 检查 node：拒绝未知字段和重复字段，
+// 并逐个解码标量以报告精确的类型错误位置。
+func (v *validator) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	v.nodes[path] = node
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		v.decodeScalar(node, t, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, path, "expected a mapping")
+			return
+		}
+		fields, inline := structFields(t)
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinPath(path, key.Value)
+			if prev, ok := seen[key.Value]; ok {
+				v.add(key, childPath, "duplicate field (first defined on line %d)", prev.Line)
+				continue
+			}
+			seen[key.Value] = key
+
+			if f, ok := fields[key.Value]; ok {
+				v.walk(value, f.Type, childPath)
+			} else if inline != nil {
+				v.walk(value, inline.Type.Elem(), childPath)
+			} else {
+				v.add(key, childPath, "unknown field")
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(node, path, "expected a list")
+			return
+		}
+		for i, elem := range node.Content {
+			v.walk(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	default:
+		v.decodeScalar(node, t, path)
+	}
+}
+
+var yamlLinePrefix = regexp.MustCompile(`^(yaml: )?line \d+: `)
+
+func (v *validator) decodeScalar(node *yaml.Node, t reflect.Type, path string) {
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		msg := err.Error()
+		if te, ok := err.(*yaml.TypeError); ok && len(te.Errors) > 0 {
+			msg = te.Errors[0]
+		}
+		v.add(node, path, "%s", yamlLinePrefix.ReplaceAllString(msg, ""))
+	}
+}
+
+// structFields 返回结构体按 yaml 标签索引的字段，以及 ",inline" 的 map 字段
+func structFields(t reflect.Type) (map[string]reflect.StructField, *reflect.StructField) {
+	fields := make(map[string]reflect.StructField)
+	var inline *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") && f.Type.Kind() == reflect.Map {
+			inline = &f
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields, inline
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// ValidateFeed 按 Load 的规则检查要加入配置的订阅源，如 OPML 导入的订阅源。
+// 不检查 ID 是否重复和渠道引用，由调用方负责
+func ValidateFeed(feed Feed) error {
+	v := newValidator()
+	if feed.ID == "" {
+		v.errorf("id", "id is required")
+	}
+	v.checkFeed("", feed)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(v.errs))
+	for i, e := range v.errs {
+		msgs[i] = e.Path + ": " + e.Msg
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// check 做字段之间、字段取值的语义检查
+func (v *validator) check(cfg *Config) {
+	channelNames := make(map[string]string)
+	for i, ch := range cfg.Channels {
+		path := fmt.Sprintf("channels[%d]", i)
+		if ch.Name == "" {
+			v.errorf(path+".name", "name is required")
+		} else if first, ok := channelNames[ch.Name]; ok {
+			v.errorf(path+".name", "duplicate channel name %q (first defined at %s)", ch.Name, first)
+		} else {
+			channelNames[ch.Name] = path
+		}
+
+		if ch.Type == "" {
+			v.errorf(path+".type", "type is required")
+		} else if !contains(ChannelTypes, ch.Type) {
+			v.errorf(path+".type", "unknown channel type %q (want one of %s)", ch.Type, strings.Join(ChannelTypes, ", "))
+		}
+	}
+	// 未配置渠道时使用隐式的 bark 渠道
+	if len(cfg.Channels) == 0 {
+		channelNames["bark"] = ""
+	}
+
+	v.checkOutput(cfg.Output)
+
+	ids := make(map[string]string)
+	for i, feed := range cfg.Feeds {
+		path := fmt.Sprintf("feeds[%d]", i)
+
+		if feed.ID == "" {
+			v.errorf(path+".id", "id is required")
+		} else if first, ok := ids[feed.ID]; ok {
+			v.errorf(path+".id", "duplicate feed id %q (first defined at %s)", feed.ID, first)
+		} else {
+			ids[feed.ID] = path
+		}
+
+		v.checkFeed(path, feed)
+
+		for j, name := range feed.Channels {
+			if _, ok := channelNames[name]; !ok {
+				v.errorf(fmt.Sprintf("%s.channels[%d]", path, j), "unknown channel %q", name)
+			}
+		}
+	}
+}
+
+// checkFeed 检查单个订阅源的取值，ID 和渠道引用由 check 检查
+func (v *validator) checkFeed(path string, feed Feed) {
+	if feed.Name == "" {
+		v.errorf(path+".name", "name is required")
+	}
+
+	switch {
+	case feed.URL == "" && feed.Site == "":
+		v.errorf(path+".url", "one of url or site is required")
+	case feed.URL != "" && feed.Site != "":
+		v.errorf(path+".site", "url and site are mutually exclusive")
+	case feed.URL != "":
+		v.checkURL(path+".url", feed.URL)
+	default:
+		v.checkURL(path+".site", feed.Site)
+	}
+
+	if feed.DedupeKey != "" && !contains(dedupeKeys, feed.DedupeKey) {
+		v.errorf(path+".dedupe_key", "unknown dedupe_key %q (want one of %s)", feed.DedupeKey, strings.Join(dedupeKeys, ", "))
+	}
+	if feed.AggregateWindowMinutes < 0 {
+		v.errorf(path+".aggregate_window_minutes", "must not be negative")
+	}
+	if feed.SeenRetention < 0 {
+		v.errorf(path+".seen_retention", "must not be negative")
+	}
+	if feed.Interval < 0 {
+		v.errorf(path+".interval", "must not be negative")
+	}
+	if feed.AlertAfterFailures < 0 {
+		v.errorf(path+".alert_after_failures", "must not be negative")
+	}
+	if feed.StaleAfter < 0 {
+		v.errorf(path+".stale_after", "must not be negative")
+	}
+	if feed.MediaMaxSizeMB < 0 {
+		v.errorf(path+".media_max_size_mb", "must not be negative")
+	}
+	if !feed.Media && (feed.MediaDownloadDir != "" || feed.MediaMaxSizeMB != 0) {
+		v.errorf(path+".media", "media_download_dir and media_max_size_mb require media: true")
+	}
+
+	v.checkFilterRules(path+".filters.include", feed.Filters.Include)
+	v.checkFilterRules(path+".filters.exclude", feed.Filters.Exclude)
+}
+
+func (v *validator) checkOutput(out Output) {
+	if out == (Output{}) {
+		return
+	}
+	if out.Path == "" {
+		v.errorf("output.path", "path is required")
+	}
+	if out.Format != "" && !contains(outputFormats, out.Format) {
+		v.errorf("output.format", "unknown format %q (want one of %s)", out.Format, strings.Join(outputFormats, ", "))
+	}
+	if out.Order != "" && !contains(outputOrders, out.Order) {
+		v.errorf("output.order", "unknown order %q (want one of %s)", out.Order, strings.Join(outputOrders, ", "))
+	}
+	if out.MaxItems < 0 {
+		v.errorf("output.max_items", "must not be negative")
+	}
+	if out.Link != "" {
+		v.checkURL("output.link", out.Link)
+	}
+}
+
+func (v *validator) checkURL(path, s string) {
+	if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(path, "invalid url %q (want an absolute http or https URL)", s)
+	}
+}
+
+func (v *validator) checkFilterRules(path string, rules []FilterRule) {
+	for i, rule := range rules {
+		rulePath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case rule.Keyword == "" && rule.Regex == "":
+			v.errorf(rulePath, "one of keyword or regex is required")
+		case rule.Keyword != "" && rule.Regex != "":
+			v.errorf(rulePath, "keyword and regex are mutually exclusive")
+		case rule.Regex != "":
+			if _, err := regexp.Compile(rule.Regex); err != nil {
+				v.errorf(rulePath+".regex", "invalid regex: %v", err)
+			}
+		}
+		for j, field := range rule.Fields {
+			if !contains(FilterFields, field) {
+				v.errorf(fmt.Sprintf("%s.fields[%d]", rulePath, j), "unknown field %q (want one of %s)", field, strings.Join(FilterFields, ", "))
+			}
+		}
+	}
+}
+
+// applyDefaults 为未设置的字段填入默认值，是唯一设置默认值的地方
+func (v *validator) applyDefaults(cfg *Config) {
+	if out := &cfg.Output; out.Path != "" {
+		if out.Format == "" {
+			out.Format = defaultOutputFormat
+		}
+		if out.Title == "" {
+			out.Title = defaultOutputTitle
+		}
+		if !v.has("output.max_items") {
+			out.MaxItems = defaultOutputMaxItems
+		}
+		if out.Order == "" {
+			out.Order = defaultOutputOrder
+		}
+	}
+
+	for i := range cfg.Feeds {
+		feed := &cfg.Feeds[i]
+		path := fmt.Sprintf("feeds[%d]", i)
+
+		if feed.DedupeKey == "" {
+			feed.DedupeKey = defaultDedupeKey
+		}
+		if !v.has(path + ".notify") {
+			feed.Notify = true
+		}
+		if feed.Aggregate && !v.has(path+".aggregate_window_minutes") {
+			feed.AggregateWindowMinutes = defaultAggregateWindowMinutes
+		}
+		if !v.has(path + ".alert_after_failures") {
+			feed.AlertAfterFailures = defaultAlertAfterFailures
+		}
+	}
+}
+
+func (e Errors) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].Line != e[j].Line {
+			return e[i].Line < e[j].Line
+		}
+		return e[i].Column < e[j].Column
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
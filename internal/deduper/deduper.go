@@ -1,56 +1,87 @@
 package deduper
 
 import (
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
 	"github.com/rsswatcher/rsswatcher/internal/parser"
 	"github.com/rsswatcher/rsswatcher/internal/state"
 )
 
+// DefaultRetention 是每个订阅源默认保留的已见条目数
+const DefaultRetention = 500
+
 type Deduper struct {
 	state *state.State
+	now   func() time.Time
 }
 
 func New(s *state.State) *Deduper {
 	return &Deduper{
 		state: s,
+		now:   time.Now,
 	}
 }
 
-func (d *Deduper) GetNewItems(feedID string, items []*parser.Item, dedupeKey string) []*parser.Item {
+// GetNewItems 返回 items 中尚未见过的条目，并把本次所有条目记为已见。
+// retention 是保留的已见 key 上限，<= 0 时使用 DefaultRetention。
+func (d *Deduper) GetNewItems(feedID string, items []*parser.Item, dedupeKey string, retention int) []*parser.Item {
 	if len(items) == 0 {
 		return nil
 	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
 
-	lastSeen := d.state.Get(feedID)
-	if lastSeen == "" {
-		firstItem := items[0]
-		d.state.Set(feedID, d.getItemKey(firstItem, dedupeKey))
-		return []*parser.Item{firstItem}
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = ItemKey(item, dedupeKey)
 	}
 
-	newItems := make([]*parser.Item, 0)
-	foundLast := false
+	seen := d.state.SeenKeys(feedID)
+	lastSeen := d.state.Get(feedID)
 
-	for _, item := range items {
-		itemKey := d.getItemKey(item, dedupeKey)
-		if itemKey == lastSeen {
-			foundLast = true
+	var newItems []*parser.Item
+	switch {
+	case len(seen) > 0:
+		newItems = make([]*parser.Item, 0)
+		reported := make(map[string]bool)
+		for i, item := range items {
+			if _, ok := seen[keys[i]]; ok || reported[keys[i]] {
+				continue
+			}
+			reported[keys[i]] = true
+			newItems = append(newItems, item)
+		}
+	case lastSeen != "":
+		// 从旧版单 key 状态迁移：沿用旧逻辑判断本次的新条目。
+		// 旧 key 已不在订阅源中时无法判断，只记录当前条目而不通知，以免刷屏
+		newItems = make([]*parser.Item, 0)
+		if !contains(keys, lastSeen) {
 			break
 		}
-		newItems = append(newItems, item)
+		for i, item := range items {
+			if keys[i] == lastSeen {
+				break
+			}
+			newItems = append(newItems, item)
+		}
+	default:
+		// 首次运行只通知最新的一条，避免刷屏
+		newItems = []*parser.Item{items[0]}
 	}
 
-	if len(newItems) > 0 {
-		firstItem := newItems[0]
-		d.state.Set(feedID, d.getItemKey(firstItem, dedupeKey))
-	} else if !foundLast && len(items) > 0 {
-		firstItem := items[0]
-		d.state.Set(feedID, d.getItemKey(firstItem, dedupeKey))
+	metrics.NewItems.Add(float64(len(newItems)), feedID)
+	d.state.MarkSeen(feedID, keys, d.now(), retention)
+	if lastSeen != "" {
+		d.state.Set(feedID, "")
 	}
 
 	return newItems
 }
 
-func (d *Deduper) getItemKey(item *parser.Item, dedupeKey string) string {
+// ItemKey 返回条目在订阅源内的去重 key，dedupeKey 为订阅源配置的 dedupe_key
+func ItemKey(item *parser.Item, dedupeKey string) string {
 	switch dedupeKey {
 	case "guid":
 		if item.GUID != "" {
@@ -68,3 +99,12 @@ func (d *Deduper) getItemKey(item *parser.Item, dedupeKey string) string {
 		return item.Link
 	}
 }
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
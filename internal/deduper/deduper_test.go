@@ -0,0 +1,130 @@
+package deduper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+	"github.com/rsswatcher/rsswatcher/internal/state"
+)
+
+func items(guids ...string) []*parser.Item {
+	out := make([]*parser.Item, len(guids))
+	for i, g := range guids {
+		out[i] = &parser.Item{GUID: g}
+	}
+	return out
+}
+
+func guids(items []*parser.Item) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.GUID
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDeduper_FirstRun(t *testing.T) {
+	d := New(state.New())
+
+	got := guids(d.GetNewItems("feed", items("c", "b", "a"), "guid", 0))
+	if !equal(got, []string{"c"}) {
+		t.Errorf("first run = %v, want [c]", got)
+	}
+
+	got = guids(d.GetNewItems("feed", items("c", "b", "a"), "guid", 0))
+	if len(got) != 0 {
+		t.Errorf("second run = %v, want none", got)
+	}
+}
+
+func TestDeduper_OrderIndependent(t *testing.T) {
+	d := New(state.New())
+	d.GetNewItems("feed", items("c", "b", "a"), "guid", 0)
+
+	// 顶部条目被删除，新条目插入到中间
+	got := guids(d.GetNewItems("feed", items("b", "x", "a"), "guid", 0))
+	if !equal(got, []string{"x"}) {
+		t.Errorf("GetNewItems() = %v, want [x]", got)
+	}
+
+	// 重新排序不应产生新条目
+	got = guids(d.GetNewItems("feed", items("a", "x", "b", "c"), "guid", 0))
+	if len(got) != 0 {
+		t.Errorf("GetNewItems() after reorder = %v, want none", got)
+	}
+}
+
+func TestDeduper_MigratesLegacyState(t *testing.T) {
+	s := state.New()
+	s.Set("feed", "b")
+	d := New(s)
+
+	got := guids(d.GetNewItems("feed", items("d", "c", "b", "a"), "guid", 0))
+	if !equal(got, []string{"d", "c"}) {
+		t.Errorf("GetNewItems() = %v, want [d c]", got)
+	}
+	if s.Get("feed") != "" {
+		t.Errorf("legacy key not cleared: %q", s.Get("feed"))
+	}
+	if n := len(s.SeenKeys("feed")); n != 4 {
+		t.Errorf("len(SeenKeys) = %d, want 4", n)
+	}
+}
+
+func TestDeduper_MigratesLegacyStateMissingKey(t *testing.T) {
+	s := state.New()
+	s.Set("feed", "gone")
+	d := New(s)
+
+	// 旧 key 已滚出订阅源，不应把所有条目当作新条目
+	if got := guids(d.GetNewItems("feed", items("c", "b", "a"), "guid", 0)); len(got) != 0 {
+		t.Errorf("GetNewItems() = %v, want none", got)
+	}
+	if n := len(s.SeenKeys("feed")); n != 3 {
+		t.Errorf("len(SeenKeys) = %d, want 3", n)
+	}
+	if got := guids(d.GetNewItems("feed", items("d", "c", "b", "a"), "guid", 0)); !equal(got, []string{"d"}) {
+		t.Errorf("GetNewItems() = %v, want [d]", got)
+	}
+}
+
+func TestDeduper_Retention(t *testing.T) {
+	s := state.New()
+	d := New(s)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	d.GetNewItems("feed", items("a", "b"), "guid", 3)
+	now = now.Add(time.Hour)
+	d.GetNewItems("feed", items("c", "d"), "guid", 3)
+
+	seen := s.SeenKeys("feed")
+	if len(seen) != 3 {
+		t.Fatalf("len(SeenKeys) = %d, want 3", len(seen))
+	}
+	for _, k := range []string{"c", "d"} {
+		if _, ok := seen[k]; !ok {
+			t.Errorf("key %q pruned, want kept", k)
+		}
+	}
+
+	// 当前条目数超过上限时全部保留
+	now = now.Add(time.Hour)
+	d.GetNewItems("feed", items("e", "f", "g", "h"), "guid", 3)
+	if n := len(s.SeenKeys("feed")); n != 4 {
+		t.Errorf("len(SeenKeys) = %d, want 4", n)
+	}
+}
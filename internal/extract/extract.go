@@ -0,0 +1,267 @@
+// Package extract 从文章网页中提取正文，算法参考 Readability：
+// 按段落文本为其父节点打分，选出得分最高的节点作为正文容器。
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNoContent 表示网页中没有找到足够长的正文
+var ErrNoContent = errors.New("no article content found")
+
+// minTextLen 是正文的最小长度（字符数），更短时视为提取失败
+const minTextLen = 100
+
+// minParagraphLen 是参与打分的段落的最小长度
+const minParagraphLen = 25
+
+var (
+	// unlikely 匹配通常不是正文的节点的 class 或 id
+	unlikely = regexp.MustCompile(`(?i)comment|sidebar|footer|footnote|nav|menu|share|social|related|advert|banner|sponsor|popup|cookie|subscribe|breadcrumb|pagination|widget`)
+	// maybe 匹配可能是正文的节点，优先于 unlikely
+	maybe    = regexp.MustCompile(`(?i)article|content|post|entry|main|body|text|story|column`)
+	positive = regexp.MustCompile(`(?i)article|content|post|entry|main|body|text|story|blog`)
+	negative = regexp.MustCompile(`(?i)comment|sidebar|footer|nav|menu|share|social|related|advert|banner|sponsor|widget|meta|tag`)
+)
+
+// removed 是提取前整体删除的元素
+var removed = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Iframe: true, atom.Svg: true, atom.Button: true,
+	atom.Select: true, atom.Textarea: true, atom.Input: true,
+}
+
+// blocks 是输出文本时需要换段的元素
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Tr: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Hr: true, atom.Br: true,
+}
+
+// Text 从网页中提取正文纯文本，段落之间以空行分隔。contentType 是响应的
+// Content-Type，其中的 charset 和网页中的 <meta charset> 用于把 GBK 等编码转换为 UTF-8
+func Text(body []byte, contentType string) (string, error) {
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	prune(doc)
+
+	best := bestCandidate(doc)
+	if best == nil {
+		return "", ErrNoContent
+	}
+
+	text := render(best)
+	if utf8.RuneCountInString(text) < minTextLen {
+		return "", ErrNoContent
+	}
+	return text, nil
+}
+
+// prune 删除脚本、导航等元素，以及 class/id 看起来不是正文的节点
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && shouldRemove(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func shouldRemove(n *html.Node) bool {
+	if removed[n.DataAtom] {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	id := classAndID(n)
+	return id != "" && unlikely.MatchString(id) && !maybe.MatchString(id)
+}
+
+func classAndID(n *html.Node) string {
+	return strings.TrimSpace(attr(n, "class") + " " + attr(n, "id"))
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// bestCandidate 为每个段落的父节点和祖父节点打分，返回得分最高的节点
+func bestCandidate(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	addScore := func(n *html.Node, s float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			order = append(order, n)
+		}
+		scores[n] += s
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := collapse(innerText(n))
+			if l := utf8.RuneCountInString(text); l >= minParagraphLen {
+				s := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) + min(float64(l)/100, 3)
+				addScore(n.Parent, s)
+				if n.Parent != nil {
+					addScore(n.Parent.Parent, s/2)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		s := scores[n] * (1 - linkDensity(n))
+		if best == nil || s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	return best
+}
+
+func initialScore(n *html.Node) float64 {
+	var s float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		s = 10
+	case atom.Div:
+		s = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		s = 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Address:
+		s = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		s = -5
+	}
+	if id := classAndID(n); id != "" {
+		if positive.MatchString(id) {
+			s += 25
+		}
+		if negative.MatchString(id) {
+			s -= 25
+		}
+	}
+	return s
+}
+
+// linkDensity 是节点文本中链接文本所占的比例
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(collapse(innerText(n)))
+	if total == 0 {
+		return 0
+	}
+	var links int
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += utf8.RuneCountInString(collapse(innerText(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// render 输出节点的文本，块级元素之间分段，段内空白折叠为一个空格
+func render(n *html.Node) string {
+	var paragraphs []string
+	var cur strings.Builder
+
+	flush := func() {
+		if p := collapse(cur.String()); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		cur.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			cur.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			// 预格式化文本保留原有换行
+			if n.DataAtom == atom.Pre {
+				flush()
+				if p := strings.Trim(innerText(n), "\n"); strings.TrimSpace(p) != "" {
+					paragraphs = append(paragraphs, p)
+				}
+				return
+			}
+			if blocks[n.DataAtom] {
+				flush()
+				defer flush()
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
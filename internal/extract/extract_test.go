@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	for _, name := range []string{"blog", "notice"} {
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", name+".html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Text(input, "text/html")
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("Text() =\n%s\n\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestText_NoContent(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "empty.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Text(input, "text/html"); !errors.Is(err, ErrNoContent) {
+		t.Errorf("Text() error = %v, want ErrNoContent", err)
+	}
+}
+
+func TestText_GBK(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "notice-gbk.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "notice.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 去掉 meta 后只能依靠响应头中的 charset
+	noMeta := bytes.Replace(input, []byte(`<meta http-equiv="Content-Type" content="text/html; charset=gb2312">`), nil, 1)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+	}{
+		{"meta", input, "text/html"},
+		{"header", noMeta, "text/html; charset=GBK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Text(tt.body, tt.contentType)
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("Text() =\n%s\n\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxPageBody 限制 Get 读取的网页大小
+const maxPageBody = 2 << 20
+
+// feedTypes 是 <link rel="alternate"> 中表示订阅源的 MIME 类型
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// commonPaths 是网页没有声明订阅源时尝试的常见路径
+var commonPaths = []string{
+	"/feed",
+	"/rss",
+	"/feed.xml",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// Candidate 是自动发现的订阅源地址
+type Candidate struct {
+	URL   string
+	Type  string
+	Title string
+	// Source 为 "link" 表示来自网页的 <link> 标签，"probe" 表示来自常见路径探测
+	Source string
+}
+
+// Discover 从网站地址查找订阅源。地址本身就是订阅源时直接返回它。
+func (f *Fetcher) Discover(ctx context.Context, siteURL string) ([]Candidate, error) {
+	page, err := f.Get(ctx, siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsHTML(page.ContentType, page.Body) {
+		if IsFeed(page.ContentType, page.Body) {
+			return []Candidate{{URL: page.URL, Type: page.ContentType, Source: "link"}}, nil
+		}
+		return nil, fmt.Errorf("%s is neither a web page nor a feed", siteURL)
+	}
+
+	return f.DiscoverFromHTML(ctx, page.URL, page.Body)
+}
+
+// DiscoverFromHTML 扫描网页中的 <link rel="alternate"> 标签，
+// 没有找到时依次探测常见的订阅源路径
+func (f *Fetcher) DiscoverFromHTML(ctx context.Context, pageURL string, body []byte) ([]Candidate, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if candidates := parseFeedLinks(base, body); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	var candidates []Candidate
+	// 多个路径可能重定向到同一个订阅源
+	seen := make(map[string]bool)
+	for _, p := range commonPaths {
+		u := base.ResolveReference(&url.URL{Path: p}).String()
+		page, err := f.Get(ctx, u)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if IsFeed(page.ContentType, page.Body) && !seen[page.URL] {
+			seen[page.URL] = true
+			candidates = append(candidates, Candidate{URL: page.URL, Type: page.ContentType, Source: "probe"})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no feed found at %s", pageURL)
+	}
+	return candidates, nil
+}
+
+func parseFeedLinks(base *url.URL, body []byte) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return candidates
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			if tag == "body" {
+				return candidates
+			}
+			if tag != "link" || !hasAttr {
+				continue
+			}
+
+			attrs := make(map[string]string)
+			for {
+				key, val, more := z.TagAttr()
+				attrs[strings.ToLower(string(key))] = string(val)
+				if !more {
+					break
+				}
+			}
+
+			if !isFeedLink(attrs) {
+				continue
+			}
+			href, err := url.Parse(strings.TrimSpace(attrs["href"]))
+			if err != nil {
+				continue
+			}
+			u := base.ResolveReference(href).String()
+			if seen[u] {
+				continue
+			}
+			seen[u] = true
+			candidates = append(candidates, Candidate{
+				URL:    u,
+				Type:   strings.ToLower(attrs["type"]),
+				Title:  attrs["title"],
+				Source: "link",
+			})
+		}
+	}
+}
+
+func isFeedLink(attrs map[string]string) bool {
+	if attrs["href"] == "" {
+		return false
+	}
+	rels := strings.Fields(strings.ToLower(attrs["rel"]))
+	alternate := false
+	for _, r := range rels {
+		if r == "alternate" {
+			alternate = true
+		}
+	}
+	if !alternate {
+		return false
+	}
+	typ := strings.ToLower(strings.TrimSpace(attrs["type"]))
+	if feedTypes[typ] {
+		return true
+	}
+	// 部分站点用 application/json 声明 JSON Feed
+	return typ == "application/json" && strings.Contains(strings.ToLower(attrs["href"]), "feed")
+}
+
+// Page 是一次普通 GET 的结果，URL 为跟随重定向后的最终地址
+type Page struct {
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+// Get 下载网页，不使用条件请求也不重试，内容最多读取 2 MiB
+func (f *Fetcher) Get(ctx context.Context, u string) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	release, err := f.hosts.acquire(ctx, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, f.httpError(req.URL.Host, resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBody))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
+
+// IsHTML 根据 Content-Type 和内容判断响应是否为网页
+func IsHTML(contentType string, body []byte) bool {
+	switch mediaType(contentType) {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+	head := bytes.ToLower(bytes.TrimSpace(sniffHead(body)))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+// IsFeed 根据 Content-Type 和内容判断响应是否为 RSS、Atom 或 JSON Feed
+func IsFeed(contentType string, body []byte) bool {
+	switch mediaType(contentType) {
+	case "application/rss+xml", "application/atom+xml", "application/feed+json", "application/rdf+xml":
+		return true
+	}
+	head := bytes.ToLower(sniffHead(body))
+	for _, marker := range []string{"<rss", "<feed", "<rdf:rdf", "jsonfeed.org/version"} {
+		if bytes.Contains(head, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sniffHead(body []byte) []byte {
+	if len(body) > 1024 {
+		return body[:1024]
+	}
+	return body
+}
@@ -0,0 +1,99 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscover_LinkTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="RSS" href="/index.xml">
+<link rel="alternate" type="application/atom+xml" href="https://example.com/atom.xml">
+<link rel="alternate" type="text/html" hreflang="en" href="/en/">
+</head><body></body></html>`))
+	}))
+	defer srv.Close()
+
+	got, err := newTestFetcher().Discover(context.Background(), srv.URL+"/blog/")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Discover() = %+v, want 2 candidates", got)
+	}
+	if got[0].URL != srv.URL+"/index.xml" || got[0].Title != "RSS" || got[0].Source != "link" {
+		t.Errorf("candidate[0] = %+v", got[0])
+	}
+	if got[1].URL != "https://example.com/atom.xml" || got[1].Type != "application/atom+xml" {
+		t.Errorf("candidate[1] = %+v", got[1])
+	}
+}
+
+func TestDiscover_ProbeCommonPaths(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>blog</title></head><body></body></html>"))
+		case "/atom.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+		case "/rss", "/rss.xml":
+			// 重定向到同一个订阅源的路径只算一个候选
+			http.Redirect(w, r, "/atom.xml", http.StatusMovedPermanently)
+		case "/feed":
+			// 返回网页的路径不应被当作订阅源
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := newTestFetcher().Discover(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(got) != 1 || got[0].URL != srv.URL+"/atom.xml" || got[0].Source != "probe" {
+		t.Errorf("Discover() = %+v", got)
+	}
+}
+
+func TestDiscover_FeedURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	got, err := newTestFetcher().Discover(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(got) != 1 || got[0].URL != srv.URL {
+		t.Errorf("Discover() = %+v", got)
+	}
+}
+
+func TestDiscover_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>hello</body></html>"))
+	}))
+	defer srv.Close()
+
+	if _, err := newTestFetcher().Discover(context.Background(), srv.URL); err == nil {
+		t.Error("Discover() error = nil, want error")
+	}
+}
@@ -2,10 +2,15 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
 	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
 )
 
 const (
@@ -14,51 +19,147 @@ const (
 	userAgent      = "RSSWatcher/1.0 (+https://github.com/rsswatcher/rsswatcher)"
 )
 
+// Validators 是条件请求使用的缓存校验值
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Response 是一次抓取的结果。NotModified 为 true 时 Body 为空，
+// 调用方应跳过解析。
+type Response struct {
+	Body        []byte
+	NotModified bool
+	Validators  Validators
+	// URL 是跟随重定向后的最终地址
+	URL string
+	// PermanentURL 是从请求地址起连续经过 301/308 重定向到达的地址，
+	// 说明订阅源已永久迁移；没有永久重定向时为空
+	PermanentURL string
+	ContentType  string
+	StatusCode   int
+}
+
+// HTTPError 表示服务器返回了非预期的状态码
+type HTTPError struct {
+	StatusCode int
+	URL        string
+	// RetryAfter 是 429/503 响应中 Retry-After 要求的等待时间，未提供时为 0
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+type feedIDKey struct{}
+
+// WithFeedID 在 ctx 中记录订阅源 ID，用作抓取指标的标签
+func WithFeedID(ctx context.Context, feedID string) context.Context {
+	return context.WithValue(ctx, feedIDKey{}, feedID)
+}
+
+func feedID(ctx context.Context) string {
+	if id, ok := ctx.Value(feedIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
 type Fetcher struct {
 	client  *http.Client
 	retries int
+	backoff time.Duration // 第一次重试前的基准等待时间
+	hosts   *hostLimiter
 }
 
 func New() *Fetcher {
 	return &Fetcher{
 		client: &http.Client{
-			Timeout: defaultTimeout,
+			Timeout:       defaultTimeout,
+			CheckRedirect: checkRedirect,
 		},
 		retries: defaultRetries,
+		backoff: defaultBackoff,
+		hosts:   newHostLimiter(defaultHostConcurrency, defaultHostDelay),
 	}
 }
 
-func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+// LimitHosts 设置对同一主机的最大并发请求数和相邻请求的最小间隔，
+// 须在开始抓取前调用
+func (f *Fetcher) LimitHosts(concurrency int, delay time.Duration) {
+	f.hosts = newHostLimiter(concurrency, delay)
+}
+
+// Fetch 抓取 url。暂时性错误（见 IsTransient）按指数退避加随机抖动重试，
+// 永久错误立即返回。返回的错误包装了原始错误，可用 errors.As 取得 *HTTPError。
+// ctx 被取消或超时时直接返回 ctx.Err()。
+func (f *Fetcher) Fetch(ctx context.Context, url string, v Validators) (*Response, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= f.retries; attempt++ {
 		if attempt > 0 {
-			backoff := time.Duration(attempt*attempt) * time.Second
 			select {
-			case <-time.After(backoff):
+			case <-time.After(backoffDelay(attempt, f.backoff, rand.Float64())):
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			}
 		}
 
-		data, err := f.fetchOnce(ctx, url)
+		resp, err := f.fetchOnce(ctx, url, v)
 		if err == nil {
-			return data, nil
+			return resp, nil
+		}
+
+		// 调用方取消或超时不是订阅源的问题，原样返回，不计入抓取错误
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
 
 		lastErr = err
+		slog.Debug("fetch attempt failed", "feed_id", feedID(ctx), "stage", "fetch", "url", url, "attempt", attempt+1, "error", err)
+
+		if !IsTransient(err) {
+			metrics.FetchErrors.Inc(feedID(ctx))
+			return nil, fmt.Errorf("permanent error: %w", err)
+		}
+
+		// 要求等待太久时不在本次运行中重试，由调用方推迟下次轮询
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > maxRetryAfter {
+			metrics.FetchErrors.Inc(feedID(ctx))
+			return nil, fmt.Errorf("server asked to retry after %s: %w", httpErr.RetryAfter, err)
+		}
 	}
 
+	metrics.FetchErrors.Inc(feedID(ctx))
 	return nil, fmt.Errorf("failed after %d retries: %w", f.retries, lastErr)
 }
 
-func (f *Fetcher) fetchOnce(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+func (f *Fetcher) fetchOnce(ctx context.Context, url string, v Validators) (*Response, error) {
+	var redir redirects
+	req, err := http.NewRequestWithContext(withRedirects(ctx, &redir), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", userAgent)
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	release, err := f.hosts.acquire(ctx, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// 只统计请求本身的耗时，不含排队等待
+	start := time.Now()
+	defer func() { metrics.FetchDuration.Observe(time.Since(start).Seconds(), feedID(ctx)) }()
 
 	resp, err := f.client.Do(req)
 	if err != nil {
@@ -66,9 +167,63 @@ func (f *Fetcher) fetchOnce(ctx context.Context, url string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	permanentURL := redir.permanent
+	if permanentURL == url {
+		permanentURL = ""
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// 304 可能不带校验值，此时沿用请求时的值
+		return &Response{
+			NotModified:  true,
+			Validators:   mergeValidators(v, resp.Header),
+			URL:          resp.Request.URL.String(),
+			PermanentURL: permanentURL,
+			StatusCode:   resp.StatusCode,
+		}, nil
+	case http.StatusOK:
+	default:
+		return nil, f.httpError(req.URL.Host, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	return io.ReadAll(resp.Body)
+	return &Response{
+		Body: body,
+		Validators: Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+		URL:          resp.Request.URL.String(),
+		PermanentURL: permanentURL,
+		ContentType:  resp.Header.Get("Content-Type"),
+		StatusCode:   resp.StatusCode,
+	}, nil
+}
+
+// httpError 为非预期的响应生成 HTTPError。429/503 带有 Retry-After 时，
+// 在要求的时间内暂停对该主机的请求。
+func (f *Fetcher) httpError(host string, resp *http.Response) *HTTPError {
+	e := &HTTPError{StatusCode: resp.StatusCode, URL: resp.Request.URL.String()}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if e.RetryAfter > 0 {
+			f.hosts.pause(host, min(e.RetryAfter, maxRetryAfter))
+		}
+	}
+	return e
+}
+
+func mergeValidators(v Validators, h http.Header) Validators {
+	if etag := h.Get("ETag"); etag != "" {
+		v.ETag = etag
+	}
+	if lm := h.Get("Last-Modified"); lm != "" {
+		v.LastModified = lm
+	}
+	return v
 }
@@ -0,0 +1,318 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
+)
+
+// newTestFetcher 返回请求间隔和重试等待都很短的 Fetcher，避免测试被拖慢
+func newTestFetcher() *Fetcher {
+	f := New()
+	f.LimitHosts(defaultHostConcurrency, 0)
+	f.backoff = time.Millisecond
+	return f
+}
+
+func TestFetcher_ConditionalGet(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("<rss></rss>"))
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher()
+
+	resp, err := f.Fetch(context.Background(), srv.URL, Validators{})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if resp.NotModified {
+		t.Fatal("first Fetch() NotModified = true, want false")
+	}
+	if string(resp.Body) != "<rss></rss>" {
+		t.Errorf("Body = %q", resp.Body)
+	}
+	if resp.Validators.ETag != etag || resp.Validators.LastModified != lastModified {
+		t.Errorf("Validators = %+v", resp.Validators)
+	}
+
+	resp, err = f.Fetch(context.Background(), srv.URL, resp.Validators)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if !resp.NotModified {
+		t.Fatal("second Fetch() NotModified = false, want true")
+	}
+	if len(resp.Body) != 0 {
+		t.Errorf("Body = %q, want empty", resp.Body)
+	}
+	if resp.Validators.ETag != etag || resp.Validators.LastModified != lastModified {
+		t.Errorf("Validators not carried over: %+v", resp.Validators)
+	}
+}
+
+func TestFetcher_HostLimits(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("<rss></rss>"))
+	}))
+	defer srv.Close()
+
+	const delay = 30 * time.Millisecond
+	f := New()
+	f.LimitHosts(2, delay)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Fetch(context.Background(), srv.URL, Validators{}); err != nil {
+				t.Errorf("Fetch() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrent requests = %d, want <= 2", got)
+	}
+	for i := 1; i < len(starts); i++ {
+		// 允许少量调度误差
+		if gap := starts[i].Sub(starts[i-1]); gap < delay-5*time.Millisecond {
+			t.Errorf("gap between requests %d and %d = %v, want >= %v", i-1, i, gap, delay)
+		}
+	}
+}
+
+func TestFetcher_RetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var retryAt atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		retryAt.Store(time.Now().UnixNano())
+		w.Write([]byte("<rss></rss>"))
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher()
+	start := time.Now()
+	if _, err := f.Fetch(context.Background(), srv.URL, Validators{}); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if wait := time.Unix(0, retryAt.Load()).Sub(start); wait < 2*time.Second {
+		t.Errorf("retried after %v, want >= 2s", wait)
+	}
+}
+
+func TestFetcher_RetryAfterTooLong(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher().Fetch(context.Background(), srv.URL, Validators{})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.RetryAfter != time.Hour {
+		t.Fatalf("Fetch() error = %v, want HTTPError with RetryAfter 1h", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1 (no retries)", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-5":                            0,
+		"Mon, 01 Jan 2024 08:05:00 GMT": 5 * time.Minute,
+		"Mon, 01 Jan 2024 07:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for in, want := range tests {
+		if got := parseRetryAfter(in, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestFetcher_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int // 依次返回的状态码，用完后返回 200
+		wantCalls int32
+		wantErr   int // 期望的 HTTPError 状态码，0 表示成功
+	}{
+		{"not found is permanent", []int{404}, 1, 404},
+		{"gone is permanent", []int{410}, 1, 410},
+		{"server error is retried", []int{500, 502}, 3, 0},
+		{"retries exhausted", []int{503, 503, 503}, 3, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				if n <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[n-1])
+					return
+				}
+				w.Write([]byte("<rss></rss>"))
+			}))
+			defer srv.Close()
+
+			_, err := newTestFetcher().Fetch(context.Background(), srv.URL, Validators{})
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr == 0 {
+				if err != nil {
+					t.Errorf("Fetch() error = %v", err)
+				}
+				return
+			}
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantErr {
+				t.Errorf("Fetch() error = %v, want HTTPError %d", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetcher_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(WithFeedID(context.Background(), "canceled-feed"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	// 取消不是订阅源的错误：原样返回且不计入抓取错误
+	_, err := newTestFetcher().Fetch(ctx, srv.URL, Validators{})
+	if err != context.Canceled {
+		t.Errorf("Fetch() error = %v, want context.Canceled unwrapped", err)
+	}
+	if got := metrics.FetchErrors.Value("canceled-feed"); got != 0 {
+		t.Errorf("fetch errors = %v, want 0", got)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{StatusCode: 404}, false},
+		{&HTTPError{StatusCode: 410}, false},
+		{&HTTPError{StatusCode: 429}, true},
+		{&HTTPError{StatusCode: 500}, true},
+		{&HTTPError{StatusCode: 501}, false},
+		{&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}, false},
+		{&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, false},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: io.ErrUnexpectedEOF}, true},
+		{&url.Error{Op: "parse", URL: "::", Err: errors.New("missing protocol scheme")}, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		r       float64
+		want    time.Duration
+	}{
+		{1, 0, 500 * time.Millisecond},
+		{1, 0.999999, time.Second},
+		{3, 0, 2 * time.Second},
+		{3, 0.5, 3 * time.Second},
+		{10, 0, maxBackoff / 2},
+	}
+	for _, tt := range tests {
+		got := backoffDelay(tt.attempt, time.Second, tt.r)
+		if diff := got - tt.want; diff < -time.Millisecond || diff > time.Millisecond {
+			t.Errorf("backoffDelay(%d, 1s, %v) = %v, want %v", tt.attempt, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestFetcher_PermanentRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusPermanentRedirect))
+	mux.Handle("/temp", http.RedirectHandler("/new", http.StatusFound))
+	mux.Handle("/mixed", http.RedirectHandler("/temp", http.StatusMovedPermanently))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<rss></rss>"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := map[string]string{
+		"/old":   srv.URL + "/new",
+		"/temp":  "",
+		"/mixed": srv.URL + "/temp",
+		"/new":   "",
+	}
+	f := newTestFetcher()
+	for path, want := range tests {
+		resp, err := f.Fetch(context.Background(), srv.URL+path, Validators{})
+		if err != nil {
+			t.Fatalf("Fetch(%s) error = %v", path, err)
+		}
+		if resp.PermanentURL != want {
+			t.Errorf("Fetch(%s) PermanentURL = %q, want %q", path, resp.PermanentURL, want)
+		}
+		if resp.URL != srv.URL+"/new" {
+			t.Errorf("Fetch(%s) URL = %q", path, resp.URL)
+		}
+	}
+}
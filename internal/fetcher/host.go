@@ -0,0 +1,112 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHostConcurrency = 2
+	defaultHostDelay       = time.Second
+	// maxRetryAfter 是一次运行内愿意等待的最长 Retry-After，更长时放弃重试
+	maxRetryAfter = time.Minute
+)
+
+// hostLimiter 限制对同一主机的并发请求数，并保证相邻请求之间的最小间隔
+type hostLimiter struct {
+	mu          sync.Mutex
+	concurrency int
+	delay       time.Duration
+	hosts       map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem  chan struct{}
+	next time.Time // 下一个请求最早的开始时间
+}
+
+func newHostLimiter(concurrency int, delay time.Duration) *hostLimiter {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &hostLimiter{
+		concurrency: concurrency,
+		delay:       delay,
+		hosts:       make(map[string]*hostSlot),
+	}
+}
+
+func (l *hostLimiter) slot(host string) *hostSlot {
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.hosts[host]
+	if !ok {
+		s = &hostSlot{sem: make(chan struct{}, l.concurrency)}
+		l.hosts[host] = s
+	}
+	return s
+}
+
+// acquire 等待轮到 host 的请求，返回的 release 须在响应读取完后调用
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	s := l.slot(host)
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-s.sem }
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		wait := s.next.Sub(now)
+		if wait <= 0 {
+			s.next = now.Add(l.delay)
+			l.mu.Unlock()
+			return release, nil
+		}
+		l.mu.Unlock()
+
+		// 等待期间 next 可能被 pause 推迟，醒来后重新检查
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pause 在 d 之内不再向 host 发送请求
+func (l *hostLimiter) pause(host string, d time.Duration) {
+	s := l.slot(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.next) {
+		s.next = until
+	}
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期形式的 Retry-After，无法解析时返回 0
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const maxRedirects = 10
+
+type redirectsKey struct{}
+
+// redirects 记录一次抓取经过的重定向。从请求地址开始连续的 301/308
+// 说明订阅源已永久迁移，遇到临时重定向后不再更新。
+type redirects struct {
+	permanent string
+	temporary bool
+}
+
+func (r *redirects) add(req *http.Request) {
+	if r.temporary || req.Response == nil {
+		return
+	}
+	switch req.Response.StatusCode {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		r.permanent = req.URL.String()
+	default:
+		r.temporary = true
+	}
+}
+
+func withRedirects(ctx context.Context, r *redirects) context.Context {
+	return context.WithValue(ctx, redirectsKey{}, r)
+}
+
+// checkRedirect 保持默认的最多 10 次重定向，并记录 Fetch 经过的重定向
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	if r, ok := req.Context().Value(redirectsKey{}).(*redirects); ok {
+		r.add(req)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// IsTransient 报告 err 是否可能在稍后重试时消失，如超时、连接被重置、
+// 5xx、408 和 429。404、410 等其余 4xx、域名不存在、证书错误和无效地址
+// 属于永久错误，重试没有意义。
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return transientStatus(httpErr.StatusCode)
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidCert) {
+		return false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Op == "parse" {
+		return false
+	}
+
+	// 其余网络错误（超时、连接被拒绝或重置、响应中断等）视为暂时的
+	return true
+}
+
+func transientStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code >= 500
+}
+
+// backoffDelay 返回第 attempt 次重试（从 1 开始）前的等待时间：以 base 为起点
+// 指数增长，不超过 maxBackoff，再在后一半范围内随机抖动，r 为 [0, 1) 内的随机数
+func backoffDelay(attempt int, base time.Duration, r float64) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	return d/2 + time.Duration(r*float64(d/2))
+}
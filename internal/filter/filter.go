@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+var defaultFields = []string{"title", "description"}
+
+type rule struct {
+	keyword string // 已转为小写
+	re      *regexp.Regexp
+	fields  []string
+}
+
+// Filter 按订阅源配置的 include/exclude 规则筛选条目
+type Filter struct {
+	include []rule
+	exclude []rule
+}
+
+func New(cfg config.Filters) (*Filter, error) {
+	include, err := compile(cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	exclude, err := compile(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	return &Filter{include: include, exclude: exclude}, nil
+}
+
+func compile(cfgs []config.FilterRule) ([]rule, error) {
+	rules := make([]rule, 0, len(cfgs))
+	for _, c := range cfgs {
+		r := rule{
+			keyword: strings.ToLower(c.Keyword),
+			fields:  c.Fields,
+		}
+		if len(r.fields) == 0 {
+			r.fields = defaultFields
+		}
+		if c.Regex != "" {
+			re, err := regexp.Compile(c.Regex)
+			if err != nil {
+				return nil, err
+			}
+			r.re = re
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Empty 报告是否没有配置任何规则
+func (f *Filter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Match 报告条目是否应被保留
+func (f *Filter) Match(item *parser.Item) bool {
+	if len(f.include) > 0 && !matchAny(f.include, item) {
+		return false
+	}
+	return !matchAny(f.exclude, item)
+}
+
+// Apply 返回保留的条目和被过滤的数量
+func (f *Filter) Apply(items []*parser.Item) ([]*parser.Item, int) {
+	if f.Empty() {
+		return items, 0
+	}
+	kept := make([]*parser.Item, 0, len(items))
+	for _, item := range items {
+		if f.Match(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept, len(items) - len(kept)
+}
+
+func matchAny(rules []rule, item *parser.Item) bool {
+	for _, r := range rules {
+		if r.match(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rule) match(item *parser.Item) bool {
+	for _, field := range r.fields {
+		for _, value := range fieldValues(item, field) {
+			if value == "" {
+				continue
+			}
+			if r.re != nil {
+				if r.re.MatchString(value) {
+					return true
+				}
+			} else if strings.Contains(strings.ToLower(value), r.keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldValues 返回字段的值，author 和 category 可能有多个，逐个匹配
+func fieldValues(item *parser.Item, field string) []string {
+	switch field {
+	case "title":
+		return []string{item.Title}
+	case "description":
+		return []string{item.Description}
+	case "link":
+		return []string{item.Link}
+	case "author":
+		return item.Authors
+	case "category":
+		return item.Categories
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+var notices = []*parser.Item{
+	{Title: "关于2024年本科生转专业的通知", Link: "https://example.com/jwc/1"},
+	{Title: "学术讲座：单细胞测序", Description: "生命科学学院举办讲座", Link: "https://example.com/smkxxy/2"},
+	{Title: "食堂维修公告", Link: "https://example.com/hq/3", Categories: []string{"后勤", "公告"}},
+	{Title: "Sponsored: Buy now", Link: "https://example.com/ad/4"},
+}
+
+func titles(items []*parser.Item) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.Title
+	}
+	return out
+}
+
+func TestFilter_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Filters
+		want     []string
+		filtered int
+	}{
+		{
+			name:     "no rules keeps everything",
+			want:     titles(notices),
+			filtered: 0,
+		},
+		{
+			name: "include keyword in title or description",
+			cfg: config.Filters{Include: []config.FilterRule{
+				{Keyword: "通知"},
+				{Keyword: "讲座"},
+			}},
+			want:     []string{notices[0].Title, notices[1].Title},
+			filtered: 2,
+		},
+		{
+			name: "exclude regex",
+			cfg: config.Filters{Exclude: []config.FilterRule{
+				{Regex: `(?i)^sponsored\b`},
+			}},
+			want:     []string{notices[0].Title, notices[1].Title, notices[2].Title},
+			filtered: 1,
+		},
+		{
+			name: "field targeting",
+			cfg: config.Filters{Include: []config.FilterRule{
+				{Regex: `/(jwc|smkxxy)/`, Fields: []string{"link"}},
+			}},
+			want:     []string{notices[0].Title, notices[1].Title},
+			filtered: 2,
+		},
+		{
+			name: "keyword only in description is ignored when targeting title",
+			cfg: config.Filters{Include: []config.FilterRule{
+				{Keyword: "生命科学", Fields: []string{"title"}},
+			}},
+			want:     []string{},
+			filtered: 4,
+		},
+		{
+			name: "category matches any of the item's categories",
+			cfg: config.Filters{Include: []config.FilterRule{
+				{Regex: `^公告$`, Fields: []string{"category"}},
+			}},
+			want:     []string{notices[2].Title},
+			filtered: 3,
+		},
+		{
+			name: "include and exclude combined",
+			cfg: config.Filters{
+				Include: []config.FilterRule{{Keyword: "example.com", Fields: []string{"link"}}},
+				Exclude: []config.FilterRule{{Keyword: "SPONSORED"}, {Keyword: "维修"}},
+			},
+			want:     []string{notices[0].Title, notices[1].Title},
+			filtered: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			kept, filtered := f.Apply(notices)
+			got := titles(kept)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Apply() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Apply()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if filtered != tt.filtered {
+				t.Errorf("filtered = %d, want %d", filtered, tt.filtered)
+			}
+		})
+	}
+}
+
+func TestFilter_InvalidRegex(t *testing.T) {
+	_, err := New(config.Filters{Exclude: []config.FilterRule{{Regex: "("}}})
+	if err == nil {
+		t.Error("New() with invalid regex: expected error")
+	}
+}
@@ -0,0 +1,77 @@
+// Package health 跟踪订阅源的健康状况，决定何时发送故障告警和恢复通知
+package health
+
+import (
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/state"
+)
+
+// 告警类型
+const (
+	Failing = "failing" // 连续抓取或解析失败
+	Stale   = "stale"   // 长时间没有新条目
+)
+
+// Policy 是单个订阅源的告警条件，字段为 0 时不检查对应条件
+type Policy struct {
+	MaxFailures int
+	StaleAfter  time.Duration
+}
+
+// Event 是一次更新后需要发送的通知。两者都有时应先发送恢复通知，
+// 例如抓取恢复正常但订阅源已长时间没有新条目
+type Event struct {
+	Alert     string // 新出现的告警类型
+	Recovered string // 已恢复的告警类型
+}
+
+// None 报告是否无需发送通知
+func (e Event) None() bool {
+	return e.Alert == "" && e.Recovered == ""
+}
+
+// RecordSuccess 记录一次成功的抓取，newItems 为本次的新条目数
+func RecordSuccess(h *state.Health, now time.Time, newItems int) {
+	h.LastSuccess = &now
+	h.ConsecutiveFailures = 0
+	if newItems > 0 || h.LastNewItem == nil {
+		h.LastNewItem = &now
+	}
+}
+
+// RecordFailure 记录一次失败的抓取或解析
+func RecordFailure(h *state.Health, now time.Time, errMsg string) {
+	h.ConsecutiveFailures++
+	h.LastError = errMsg
+	h.LastErrorAt = &now
+}
+
+// Check 根据 p 判断当前应处于的告警状态，并与已发送的告警 h.Alert 比较，
+// 返回需要发送的通知。每次故障只告警一次，恢复时再通知一次。
+// 调用方在通知发送成功后调用 Ack 更新 h.Alert，失败时下次运行会重试。
+func Check(h state.Health, p Policy, now time.Time) Event {
+	want := ""
+	switch {
+	case p.MaxFailures > 0 && h.ConsecutiveFailures >= p.MaxFailures:
+		want = Failing
+	case p.StaleAfter > 0 && h.LastNewItem != nil && now.Sub(*h.LastNewItem) > p.StaleAfter:
+		want = Stale
+	}
+
+	if want == h.Alert {
+		return Event{}
+	}
+	e := Event{Alert: want}
+	// 无更新告警期间出现的抓取失败不代表无更新状态已恢复
+	if h.Alert != Stale || want == "" {
+		e.Recovered = h.Alert
+	}
+	return e
+}
+
+// Ack 记录已发送的通知。只发出了 e 中的恢复通知时，
+// 传入不含 Alert 的 Event，下次运行只重试告警
+func Ack(h *state.Health, e Event) {
+	h.Alert = e.Alert
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/state"
+)
+
+func TestFailingAlert(t *testing.T) {
+	p := Policy{MaxFailures: 3}
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	var h state.Health
+	RecordSuccess(&h, now, 1)
+
+	var events []Event
+	step := func(fail bool) {
+		now = now.Add(time.Hour)
+		if fail {
+			RecordFailure(&h, now, "fetch: boom")
+		} else {
+			RecordSuccess(&h, now, 0)
+		}
+		if e := Check(h, p, now); !e.None() {
+			events = append(events, e)
+			Ack(&h, e)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		step(true)
+	}
+	if len(events) != 1 || events[0].Alert != Failing {
+		t.Fatalf("events after 5 failures = %+v, want one failing alert", events)
+	}
+	if h.ConsecutiveFailures != 5 || h.LastError != "fetch: boom" {
+		t.Errorf("health = %+v", h)
+	}
+
+	step(false)
+	if len(events) != 2 || events[1].Recovered != Failing {
+		t.Fatalf("events after recovery = %+v, want recovered", events)
+	}
+	if h.Alert != "" || h.ConsecutiveFailures != 0 {
+		t.Errorf("health after recovery = %+v", h)
+	}
+
+	step(true)
+	step(false)
+	if len(events) != 2 {
+		t.Errorf("single failure triggered events: %+v", events[2:])
+	}
+}
+
+func TestStaleAlert(t *testing.T) {
+	p := Policy{MaxFailures: 3, StaleAfter: 7 * 24 * time.Hour}
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	var h state.Health
+	RecordSuccess(&h, start, 0)
+
+	if e := Check(h, p, start.Add(6*24*time.Hour)); !e.None() {
+		t.Errorf("Check() after 6 days = %+v, want none", e)
+	}
+
+	now := start.Add(8 * 24 * time.Hour)
+	RecordFailure(&h, now, "fetch: timeout")
+	e := Check(h, p, now)
+	if e.Alert != Stale {
+		t.Fatalf("Check() after 8 days = %+v, want stale", e)
+	}
+	Ack(&h, e)
+
+	// 单次失败不影响无更新告警
+	if e := Check(h, p, now); !e.None() {
+		t.Errorf("Check() again = %+v, want none", e)
+	}
+
+	RecordSuccess(&h, now.Add(time.Hour), 2)
+	if e := Check(h, p, now.Add(time.Hour)); e.Recovered != Stale {
+		t.Errorf("Check() after new items = %+v, want recovered stale", e)
+	}
+}
+
+func TestCheck_Disabled(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := state.Health{ConsecutiveFailures: 100, LastNewItem: &old}
+	if e := Check(h, Policy{}, time.Now()); !e.None() {
+		t.Errorf("Check() with zero policy = %+v, want none", e)
+	}
+}
+
+func TestCheck_FailingToStale(t *testing.T) {
+	p := Policy{MaxFailures: 3, StaleAfter: 7 * 24 * time.Hour}
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	h := state.Health{LastNewItem: &start, ConsecutiveFailures: 3, Alert: Failing}
+
+	// 抓取恢复但仍没有新条目：先通知故障恢复，再告警无更新
+	now := start.Add(8 * 24 * time.Hour)
+	RecordSuccess(&h, now, 0)
+	e := Check(h, p, now)
+	if e.Recovered != Failing || e.Alert != Stale {
+		t.Fatalf("Check() = %+v, want recovered failing and stale alert", e)
+	}
+
+	// 只发出了恢复通知时，下次运行只重试无更新告警
+	Ack(&h, Event{Recovered: e.Recovered})
+	if e := Check(h, p, now); e.Recovered != "" || e.Alert != Stale {
+		t.Errorf("Check() after partial ack = %+v, want stale alert only", e)
+	}
+}
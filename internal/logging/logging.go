@@ -0,0 +1,27 @@
+// Package logging 根据命令行参数创建 slog logger
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// New 创建输出到 w 的 logger。level 为 debug、info、warn 或 error，
+// format 为 text 或 json。
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+}
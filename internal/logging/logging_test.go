@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.Info("processing feed", "feed_id", "blog", "stage", "fetch")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if rec["msg"] != "processing feed" || rec["feed_id"] != "blog" || rec["stage"] != "fetch" || rec["level"] != "INFO" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestNew_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "DEBUG", "text")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Debug("new item", "item_guid", "42")
+	if !strings.Contains(buf.String(), "level=DEBUG msg=\"new item\" item_guid=42") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", "text"); err == nil {
+		t.Error("New() with invalid level: error = nil")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("New() with invalid format: error = nil")
+	}
+}
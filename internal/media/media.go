@@ -0,0 +1,234 @@
+// Package media 下载播客等订阅源中的音视频附件，支持断点续传和大小限制
+package media
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+const userAgent = "RSSWatcher/1.0 (+https://github.com/rsswatcher/rsswatcher)"
+
+// partSuffix 是未下载完成的文件后缀，下次下载时从已有长度继续
+const partSuffix = ".part"
+
+// 默认超时。下载本身可能很长，因此只限制等待响应头和两次读取之间的时间
+const (
+	headerTimeout      = 30 * time.Second
+	defaultIdleTimeout = time.Minute
+)
+
+// ErrTooLarge 表示附件超过了大小限制
+var ErrTooLarge = errors.New("enclosure exceeds size limit")
+
+// errStalled 表示下载过程中长时间没有收到数据
+var errStalled = errors.New("download stalled")
+
+var unsafeChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// Downloader 把附件下载到 Dir 中
+type Downloader struct {
+	Dir string
+	// MaxSize 是单个文件的最大字节数，<= 0 表示不限制
+	MaxSize int64
+	// IdleTimeout 是下载中两次收到数据之间的最长等待时间
+	IdleTimeout time.Duration
+	client      *http.Client
+}
+
+// New 创建下载器。下载时间可能很长，因此不设置整体超时，由 ctx 控制；
+// 响应头和读取数据分别有超时，避免卡住的服务器让运行一直无法结束。
+func New(dir string, maxSize int64) *Downloader {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = headerTimeout
+	return &Downloader{
+		Dir:         dir,
+		MaxSize:     maxSize,
+		IdleTimeout: defaultIdleTimeout,
+		client:      &http.Client{Transport: transport},
+	}
+}
+
+// DownloadAll 依次下载 encs，每个附件结束后以结果调用 done。
+// ctx 取消后不再开始新的下载，返回未开始下载的附件数。
+func (d *Downloader) DownloadAll(ctx context.Context, encs []parser.Enclosure, done func(enc parser.Enclosure, path string, err error)) int {
+	for i, enc := range encs {
+		if ctx.Err() != nil {
+			return len(encs) - i
+		}
+		path, err := d.Download(ctx, enc.URL, enc.Length)
+		done(enc, path, err)
+	}
+	return 0
+}
+
+// FileName 根据附件地址生成文件名：保留原文件名，并加上地址哈希避免重名
+func FileName(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	hash := hex.EncodeToString(sum[:4])
+
+	base := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		base = path.Base(u.Path)
+	}
+	base = strings.Trim(unsafeChars.ReplaceAllString(base, "_"), "._")
+	if base == "" {
+		return hash
+	}
+
+	ext := path.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	if r := []rune(name); len(r) > 80 {
+		name = string(r[:80])
+	}
+	return name + "-" + hash + ext
+}
+
+// Download 下载 rawURL 到 Dir，返回文件路径。文件已存在时直接返回；
+// 之前中断留下的 .part 文件会通过 Range 请求续传。size 是订阅源声明的
+// 长度，超过 MaxSize 时不发起请求，未知时传 0。
+func (d *Downloader) Download(ctx context.Context, rawURL string, size int64) (string, error) {
+	dest := filepath.Join(d.Dir, FileName(rawURL))
+	part := dest + partSuffix
+	// 超过限制的附件不会再下载，删除之前留下的 .part，以免每次都续传后再次失败
+	if d.MaxSize > 0 && size > d.MaxSize {
+		os.Remove(part)
+		return "", fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, size, d.MaxSize)
+	}
+
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+
+	var offset int64
+	if fi, err := os.Stat(part); err == nil {
+		offset = fi.Size()
+	}
+
+	// 超过 IdleTimeout 没有收到数据时取消请求
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	idle := time.AfterFunc(d.IdleTimeout, func() { cancel(errStalled) })
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errStalled) {
+			return "", fmt.Errorf("%w: no response for %s", errStalled, d.IdleTimeout)
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return "", fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// 服务器不支持 Range，从头下载
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// .part 已是完整文件
+		if offset > 0 {
+			return dest, os.Rename(part, dest)
+		}
+		fallthrough
+	default:
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if d.MaxSize > 0 && resp.ContentLength > 0 && offset+resp.ContentLength > d.MaxSize {
+		os.Remove(part)
+		return "", fmt.Errorf("%w: %d > %d bytes", ErrTooLarge, offset+resp.ContentLength, d.MaxSize)
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	body := io.Reader(&idleReader{r: resp.Body, timer: idle, timeout: d.IdleTimeout})
+	if d.MaxSize > 0 {
+		body = io.LimitReader(body, d.MaxSize-offset+1)
+	}
+	n, err := io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// 保留 .part，下次续传
+		if errors.Is(context.Cause(ctx), errStalled) {
+			return "", fmt.Errorf("%w: no data for %s", errStalled, d.IdleTimeout)
+		}
+		return "", err
+	}
+	if d.MaxSize > 0 && offset+n > d.MaxSize {
+		os.Remove(part)
+		return "", fmt.Errorf("%w: more than %d bytes", ErrTooLarge, d.MaxSize)
+	}
+
+	if err := os.Rename(part, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// rangeStart 解析 "bytes 100-199/200" 中的起始位置
+func rangeStart(contentRange string) (int64, bool) {
+	s, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// idleReader 每次读到数据时重置 timer，超时后请求被取消
+type idleReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
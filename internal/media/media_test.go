@@ -0,0 +1,197 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+var episode = bytes.Repeat([]byte("0123456789"), 100)
+
+func newServer(t *testing.T, ranges *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "ep.mp3", time.Time{}, bytes.NewReader(episode))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownload(t *testing.T) {
+	var ranges []string
+	srv := newServer(t, &ranges)
+	d := New(t.TempDir(), 0)
+
+	path, err := d.Download(context.Background(), srv.URL+"/ep.mp3", 0)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, episode) {
+		t.Errorf("downloaded %d bytes, want %d", len(got), len(episode))
+	}
+
+	// 已存在的文件不再下载
+	if _, err := d.Download(context.Background(), srv.URL+"/ep.mp3", 0); err != nil {
+		t.Fatalf("second Download() error = %v", err)
+	}
+	if len(ranges) != 1 {
+		t.Errorf("requests = %d, want 1", len(ranges))
+	}
+}
+
+func TestDownload_Resume(t *testing.T) {
+	var ranges []string
+	srv := newServer(t, &ranges)
+	d := New(t.TempDir(), 0)
+
+	u := srv.URL + "/ep.mp3"
+	part := filepath.Join(d.Dir, FileName(u)) + partSuffix
+	if err := os.WriteFile(part, episode[:300], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := d.Download(context.Background(), u, 0)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=300-" {
+		t.Errorf("Range headers = %q, want [bytes=300-]", ranges)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, episode) {
+		t.Errorf("resumed file differs from original")
+	}
+	if _, err := os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf(".part file not removed")
+	}
+}
+
+func TestDownload_TooLarge(t *testing.T) {
+	var ranges []string
+	srv := newServer(t, &ranges)
+	d := New(t.TempDir(), 500)
+
+	// 声明的长度超限时不发起请求
+	if _, err := d.Download(context.Background(), srv.URL+"/a.mp3", 1000); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("declared size: error = %v, want ErrTooLarge", err)
+	}
+	if len(ranges) != 0 {
+		t.Errorf("requests = %d, want 0", len(ranges))
+	}
+
+	// 未声明长度时根据 Content-Length 拒绝
+	if _, err := d.Download(context.Background(), srv.URL+"/b.mp3", 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Content-Length: error = %v, want ErrTooLarge", err)
+	}
+	entries, _ := os.ReadDir(d.Dir)
+	if len(entries) != 0 {
+		t.Errorf("left files behind: %v", entries)
+	}
+}
+
+func TestDownload_TooLargeRemovesPart(t *testing.T) {
+	var ranges []string
+	srv := newServer(t, &ranges)
+	d := New(t.TempDir(), 500)
+
+	u := srv.URL + "/ep.mp3"
+	part := filepath.Join(d.Dir, FileName(u)) + partSuffix
+	if err := os.WriteFile(part, episode[:300], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 续传时总长度超限，.part 被删除
+	if _, err := d.Download(context.Background(), u, 0); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Download() error = %v, want ErrTooLarge", err)
+	}
+	if _, err := os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf(".part file not removed")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		url, prefix, suffix string
+	}{
+		{"https://cdn.example.com/shows/ep%2012.mp3?token=x", "ep_12-", ".mp3"},
+		{"https://cdn.example.com/", "", ""},
+		{"https://cdn.example.com/../../etc/passwd", "passwd-", ""},
+	}
+	for _, tt := range tests {
+		got := FileName(tt.url)
+		if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) || strings.ContainsAny(got, "/\\") {
+			t.Errorf("FileName(%q) = %q", tt.url, got)
+		}
+	}
+}
+
+func TestDownloadAll_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		// 第一个附件发出部分数据后卡住，直到客户端断开
+		w.Header().Set("Content-Length", "1000")
+		w.Write(episode[:10])
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	d := New(t.TempDir(), 0)
+	encs := []parser.Enclosure{{URL: srv.URL + "/1.mp3"}, {URL: srv.URL + "/2.mp3"}, {URL: srv.URL + "/3.mp3"}}
+	var errs []error
+	remaining := d.DownloadAll(ctx, encs, func(enc parser.Enclosure, path string, err error) {
+		errs = append(errs, err)
+	})
+
+	// 取消后当前下载中断，其余附件不再开始
+	if remaining != 2 {
+		t.Errorf("remaining = %d, want 2", remaining)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("errors = %v, want one context.Canceled", errs)
+	}
+	if len(requested) != 1 {
+		t.Errorf("requested = %v, want only the first enclosure", requested)
+	}
+}
+
+func TestDownload_Stalled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write(episode[:10])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	d := New(t.TempDir(), 0)
+	d.IdleTimeout = 50 * time.Millisecond
+	_, err := d.Download(context.Background(), srv.URL+"/ep.mp3", 0)
+	if !errors.Is(err, errStalled) {
+		t.Errorf("Download() error = %v, want stalled", err)
+	}
+}
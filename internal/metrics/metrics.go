@@ -0,0 +1,262 @@
+// Package metrics 实现了 Prometheus 文本格式所需的计数器、仪表和直方图，
+// 供守护进程模式下的 /metrics 接口使用。
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry 保存一组指标并按注册顺序输出
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write 以 Prometheus 文本格式输出所有指标
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler 返回输出所有指标的 HTTP handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// desc 是指标的名称、说明和标签名
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.typ)
+}
+
+// key 把标签值拼成 map 的键，标签数量不符时 panic
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelString 生成 {a="x",b="y"} 形式的标签，extra 追加在末尾
+func (d *desc) labelString(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, d.labels[i]+`="`+escape(v)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueVec 是按标签值分组的单值指标，计数器和仪表共用
+type valueVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (v *valueVec) add(delta float64, labels []string) {
+	k := v.key(labels)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] += delta
+}
+
+func (v *valueVec) set(val float64, labels []string) {
+	k := v.key(labels)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] = val
+}
+
+// Value 返回指定标签值的当前值，主要用于测试
+func (v *valueVec) Value(labels ...string) float64 {
+	k := v.key(labels)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[k]
+}
+
+func (v *valueVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.header(w)
+	for _, k := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelString(k), formatFloat(v.values[k]))
+	}
+}
+
+// CounterVec 是只增不减的计数器
+type CounterVec struct {
+	valueVec
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{valueVec{desc: desc{name, help, "counter", labels}, values: make(map[string]float64)}}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labels ...string) {
+	c.add(1, labels)
+}
+
+// Add 增加 delta，delta 为负时 panic
+func (c *CounterVec) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.add(delta, labels)
+}
+
+// GaugeVec 是可任意设置的仪表
+type GaugeVec struct {
+	valueVec
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{valueVec{desc: desc{name, help, "gauge", labels}, values: make(map[string]float64)}}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) Set(val float64, labels ...string) {
+	g.set(val, labels)
+}
+
+// DefaultBuckets 是以秒为单位的默认直方图分桶
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type histogram struct {
+	counts []uint64 // 与 buckets 一一对应，非累积
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec 是按标签值分组的直方图
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogram
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name, help, "histogram", labels},
+		buckets: append([]float64(nil), buckets...),
+		series:  make(map[string]*histogram),
+	}
+	sort.Float64s(h.buckets)
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(val float64, labels ...string) {
+	k := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, val); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += val
+}
+
+// Count 返回指定标签值的观测次数，主要用于测试
+func (h *HistogramVec) Count(labels ...string) uint64 {
+	k := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[k]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", formatFloat(b)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelString(k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelString(k), s.count)
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_errors_total", "Errors.", "feed_id")
+	g := r.NewGaugeVec("test_last_success", "Last success.", "feed_id")
+	h := r.NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "feed_id")
+	total := r.NewCounterVec("test_requests_total", "Requests.")
+
+	c.Inc("b")
+	c.Add(2, "a")
+	c.Inc(`q"x\y`)
+	g.Set(1700000000, "a")
+	h.Observe(0.05, "a")
+	h.Observe(0.5, "a")
+	h.Observe(3, "a")
+	total.Inc()
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+
+	want := `# HELP test_errors_total Errors.
+# TYPE test_errors_total counter
+test_errors_total{feed_id="a"} 2
+test_errors_total{feed_id="b"} 1
+test_errors_total{feed_id="q\"x\\y"} 1
+# HELP test_last_success Last success.
+# TYPE test_last_success gauge
+test_last_success{feed_id="a"} 1.7e+09
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{feed_id="a",le="0.1"} 1
+test_duration_seconds_bucket{feed_id="a",le="1"} 2
+test_duration_seconds_bucket{feed_id="a",le="+Inf"} 3
+test_duration_seconds_sum{feed_id="a"} 3.55
+test_duration_seconds_count{feed_id="a"} 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total 1
+`
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if got := c.Value("a"); got != 2 {
+		t.Errorf("Value(a) = %v, want 2", got)
+	}
+	if got := h.Count("a"); got != 3 {
+		t.Errorf("Count(a) = %d, want 3", got)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
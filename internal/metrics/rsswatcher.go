@@ -0,0 +1,27 @@
+package metrics
+
+// Default 是进程内各组件共用的指标注册表
+var Default = NewRegistry()
+
+var (
+	FetchDuration = Default.NewHistogramVec("rsswatcher_fetch_duration_seconds",
+		"Latency of feed HTTP requests, per attempt.", DefaultBuckets, "feed_id")
+	FetchErrors = Default.NewCounterVec("rsswatcher_fetch_errors_total",
+		"Feed fetches that failed after all retries.", "feed_id")
+	LastSuccess = Default.NewGaugeVec("rsswatcher_last_success_timestamp_seconds",
+		"Unix time of the last poll that fetched and parsed the feed.", "feed_id")
+	NewItems = Default.NewCounterVec("rsswatcher_new_items_total",
+		"Items not seen before, counted before filtering.", "feed_id")
+
+	SummarizerRequests = Default.NewCounterVec("rsswatcher_summarizer_requests_total",
+		"Summary requests sent to the AI API.")
+	SummarizerFailures = Default.NewCounterVec("rsswatcher_summarizer_failures_total",
+		"Summary requests that failed.")
+	SummarizerTokens = Default.NewCounterVec("rsswatcher_summarizer_tokens_total",
+		"Tokens reported by the AI API, by type (prompt or completion).", "type")
+
+	NotificationsSent = Default.NewCounterVec("rsswatcher_notifications_sent_total",
+		"Notification calls that succeeded, per backend.", "backend")
+	NotificationFailures = Default.NewCounterVec("rsswatcher_notification_failures_total",
+		"Notification calls that failed, per backend.", "backend")
+)
@@ -24,6 +24,23 @@ type BarkNotifier struct {
 	client    *http.Client
 }
 
+func init() {
+	Register("bark", func(opts map[string]string) (Notifier, error) {
+		b := NewBark()
+		if key := opts["device_key"]; key != "" {
+			b.deviceKey = key
+		}
+		if server := opts["server"]; server != "" {
+			b.server = server
+		}
+		if b.deviceKey == "" {
+			return nil, fmt.Errorf("missing required option(s): device_key")
+		}
+		return b, nil
+	})
+}
+
+// NewBark 从环境变量 BARK_DEVICE_KEY 和 BARK_SERVER 创建 Bark 通知器
 func NewBark() *BarkNotifier {
 	deviceKey := os.Getenv("BARK_DEVICE_KEY")
 	server := os.Getenv("BARK_SERVER")
@@ -34,9 +51,7 @@ func NewBark() *BarkNotifier {
 	return &BarkNotifier{
 		deviceKey: deviceKey,
 		server:    server,
-		client: &http.Client{
-			Timeout: notifyTimeout,
-		},
+		client:    newHTTPClient(),
 	}
 }
 
@@ -46,7 +61,7 @@ func (b *BarkNotifier) Notify(feedName string, items []*parser.Item) error {
 	}
 
 	for _, item := range items {
-		if err := b.notifyItem(feedName, item); err != nil {
+		if err := b.sendMessage(formatItem(feedName, item)); err != nil {
 			return err
 		}
 	}
@@ -54,32 +69,6 @@ func (b *BarkNotifier) Notify(feedName string, items []*parser.Item) error {
 	return nil
 }
 
-func (b *BarkNotifier) notifyItem(feedName string, item *parser.Item) error {
-	title := fmt.Sprintf("[%s] %s", feedName, truncate(item.Title, 50))
-
-	// 优先使用AI总结，否则使用原始描述
-	body := item.Summary
-	if body == "" {
-		body = truncate(item.Description, 100)
-		if body == "" {
-			body = "New item published"
-		}
-	} else {
-		// 如果使用了总结，截断到合适长度
-		body = truncate(body, 200)
-	}
-
-	opts := map[string]string{
-		"group": feedName,
-	}
-
-	if item.Link != "" {
-		opts["url"] = item.Link
-	}
-
-	return b.send(title, body, opts)
-}
-
 func (b *BarkNotifier) NotifyAggregate(feedName string, items []*parser.Item) error {
 	if b.deviceKey == "" {
 		return fmt.Errorf("BARK_DEVICE_KEY not set")
@@ -89,23 +78,22 @@ func (b *BarkNotifier) NotifyAggregate(feedName string, items []*parser.Item) er
 		return nil
 	}
 
-	title := fmt.Sprintf("[%s] %d new items", feedName, len(items))
+	return b.sendMessage(formatAggregate(feedName, items))
+}
 
-	bodyParts := make([]string, 0, len(items))
-	for i, item := range items {
-		if i >= 5 {
-			bodyParts = append(bodyParts, fmt.Sprintf("... and %d more", len(items)-5))
-			break
-		}
-		bodyParts = append(bodyParts, truncate(item.Title, 60))
+func (b *BarkNotifier) sendMessage(m message) error {
+	opts := map[string]string{
+		"group": m.Group,
 	}
-	body := strings.Join(bodyParts, "\n")
 
-	opts := map[string]string{
-		"group": feedName,
+	if m.URL != "" {
+		opts["url"] = m.URL
+	}
+	if m.Image != "" {
+		opts["image"] = m.Image
 	}
 
-	return b.send(title, body, opts)
+	return b.send(m.Title, m.Body, opts)
 }
 
 func (b *BarkNotifier) send(title, body string, opts map[string]string) error {
@@ -0,0 +1,32 @@
+package notifier
+
+import "net/http"
+
+// discord 单条消息 content 的最大长度
+const discordMaxContent = 2000
+
+// discordSender 通过 Discord Webhook 发送消息
+type discordSender struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func init() {
+	Register("discord", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "webhook_url"); err != nil {
+			return nil, err
+		}
+		return messageNotifier{&discordSender{
+			webhookURL: opts["webhook_url"],
+			client:     newHTTPClient(),
+		}}, nil
+	})
+}
+
+func (d *discordSender) send(m message) error {
+	content := "**" + m.Title + "**\n" + m.Body
+	if m.URL != "" {
+		content += "\n" + m.URL
+	}
+	return postJSON(d.client, d.webhookURL, map[string]string{"content": truncate(content, discordMaxContent)}, nil)
+}
@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const defaultSMTPPort = "587"
+
+// smtpSendMail 便于测试时替换
+var smtpSendMail = smtp.SendMail
+
+// emailSender 通过 SMTP 发送纯文本邮件
+type emailSender struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func init() {
+	Register("email", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "host", "from", "to"); err != nil {
+			return nil, err
+		}
+		port := opts["port"]
+		if port == "" {
+			port = defaultSMTPPort
+		}
+
+		var to []string
+		for _, addr := range strings.Split(opts["to"], ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+
+		return messageNotifier{&emailSender{
+			addr:     net.JoinHostPort(opts["host"], port),
+			host:     opts["host"],
+			username: opts["username"],
+			password: opts["password"],
+			from:     opts["from"],
+			to:       to,
+		}}, nil
+	})
+}
+
+func (e *emailSender) send(m message) error {
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, e.host)
+	}
+	return smtpSendMail(e.addr, auth, e.from, e.to, e.build(m))
+}
+
+func (e *emailSender) build(m message) []byte {
+	body := m.Body
+	if m.URL != "" {
+		body += "\n\n" + m.URL
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", encodeHeader(m.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// encodeHeader 对非 ASCII 的头部值做 RFC 2047 编码
+func encodeHeader(s string) string {
+	return mime.QEncoding.Encode("UTF-8", s)
+}
@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultGotifyPriority = 5
+
+// gotifySender 通过 Gotify 的 /message 接口发送消息
+type gotifySender struct {
+	server   string
+	token    string
+	priority int
+	client   *http.Client
+}
+
+func init() {
+	Register("gotify", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "server", "token"); err != nil {
+			return nil, err
+		}
+		priority := defaultGotifyPriority
+		if p := opts["priority"]; p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, err
+			}
+			priority = n
+		}
+		return messageNotifier{&gotifySender{
+			server:   strings.TrimRight(opts["server"], "/"),
+			token:    opts["token"],
+			priority: priority,
+			client:   newHTTPClient(),
+		}}, nil
+	})
+}
+
+func (g *gotifySender) send(m message) error {
+	payload := map[string]any{
+		"title":    m.Title,
+		"message":  m.Body,
+		"priority": g.priority,
+	}
+	if m.URL != "" {
+		payload["extras"] = map[string]any{
+			"client::notification": map[string]any{
+				"click": map[string]string{"url": m.URL},
+			},
+		}
+	}
+
+	header := http.Header{}
+	header.Set("X-Gotify-Key", g.token)
+
+	return postJSON(g.client, g.server+"/message", payload, header)
+}
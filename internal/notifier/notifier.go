@@ -0,0 +1,306 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+// Notifier 是通知后端需要实现的接口
+type Notifier interface {
+	Notify(feedName string, items []*parser.Item) error
+	NotifyAggregate(feedName string, items []*parser.Item) error
+}
+
+// Factory 根据渠道配置项创建通知后端
+type Factory func(opts map[string]string) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register 注册一种通知后端类型，重复注册会 panic
+func Register(kind string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[kind]; dup {
+		panic("notifier: Register called twice for " + kind)
+	}
+	registry[kind] = factory
+}
+
+// Kinds 返回已注册的后端类型，按名称排序
+func Kinds() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for k := range registry {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// New 创建指定类型的通知后端
+func New(kind string, opts map[string]string) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q", kind)
+	}
+	return factory(opts)
+}
+
+// Channel 是一个命名的通知渠道，Kind 为后端类型，用作指标标签
+type Channel struct {
+	Name string
+	Kind string
+	Notifier
+}
+
+// record 按后端类型记录一次发送结果
+func (ch Channel) record(err error) error {
+	backend := ch.Kind
+	if backend == "" {
+		backend = ch.Name
+	}
+	if err != nil {
+		metrics.NotificationFailures.Inc(backend)
+		return fmt.Errorf("%s: %w", ch.Name, err)
+	}
+	metrics.NotificationsSent.Inc(backend)
+	return nil
+}
+
+// Multi 将通知依次发送到多个渠道，单个渠道失败不影响其他渠道
+type Multi []Channel
+
+func (m Multi) Notify(feedName string, items []*parser.Item) error {
+	var errs []error
+	for _, ch := range m {
+		if err := ch.record(ch.Notify(feedName, items)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) NotifyAggregate(feedName string, items []*parser.Item) error {
+	var errs []error
+	for _, ch := range m {
+		if err := ch.record(ch.NotifyAggregate(feedName, items)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Alert 通过 n 发送一条与条目无关的提醒，如订阅源健康告警，
+// 以单条通知的格式展示，link 可为空。返回成功送达的渠道数，
+// n 为 Multi 时失败的渠道合并到返回的错误中
+func Alert(n Notifier, feedName, title, body, link string) (int, error) {
+	items := []*parser.Item{{GUID: "alert", Title: title, Description: body, Link: link}}
+	m, ok := n.(Multi)
+	if !ok {
+		if err := n.Notify(feedName, items); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	sent := 0
+	var errs []error
+	for _, ch := range m {
+		if err := ch.record(ch.Notify(feedName, items)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// message 是与后端无关的通知内容
+type message struct {
+	Title string
+	Body  string
+	URL   string
+	Group string
+	Image string // 可选的图片地址
+}
+
+func formatItem(feedName string, item *parser.Item) message {
+	title := fmt.Sprintf("[%s] %s", feedName, truncate(item.Title, 50))
+
+	// 优先使用AI总结，否则使用原始描述
+	body := item.Summary
+	if body == "" {
+		body = truncate(item.Description, 100)
+		if body == "" {
+			body = "New item published"
+		}
+	} else {
+		// 如果使用了总结，截断到合适长度
+		body = truncate(body, 200)
+	}
+
+	if info := mediaInfo(item); item.Media && info != "" {
+		body = info + "\n" + body
+	}
+
+	return message{
+		Title: title,
+		Body:  body,
+		URL:   item.Link,
+		Group: feedName,
+		Image: item.Image,
+	}
+}
+
+// mediaInfo 返回音视频附件的时长和大小，如 "42:10 · 35.2 MB"，没有附件时为空
+func mediaInfo(item *parser.Item) string {
+	enc := item.MediaEnclosure()
+	if enc == nil {
+		return ""
+	}
+	var parts []string
+	if item.Duration > 0 {
+		parts = append(parts, formatDuration(item.Duration))
+	}
+	if enc.Length > 0 {
+		parts = append(parts, formatSize(enc.Length))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func formatDuration(d time.Duration) string {
+	s := int64(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func formatAggregate(feedName string, items []*parser.Item) message {
+	title := fmt.Sprintf("[%s] %d new items", feedName, len(items))
+
+	bodyParts := make([]string, 0, len(items))
+	for i, item := range items {
+		if i >= 5 {
+			bodyParts = append(bodyParts, fmt.Sprintf("... and %d more", len(items)-5))
+			break
+		}
+		bodyParts = append(bodyParts, truncate(item.Title, 60))
+	}
+
+	return message{
+		Title: title,
+		Body:  strings.Join(bodyParts, "\n"),
+		Group: feedName,
+	}
+}
+
+// sender 是只需发送单条消息的后端
+type sender interface {
+	send(m message) error
+}
+
+// messageNotifier 把 sender 适配为 Notifier
+type messageNotifier struct {
+	sender
+}
+
+func (n messageNotifier) Notify(feedName string, items []*parser.Item) error {
+	for _, item := range items {
+		if err := n.send(formatItem(feedName, item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n messageNotifier) NotifyAggregate(feedName string, items []*parser.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return n.send(formatAggregate(feedName, items))
+}
+
+// postJSON 以 JSON 发送 payload，非 2xx 响应视为错误
+func postJSON(client *http.Client, url string, payload any, header http.Header) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	return do(client, req)
+}
+
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+func required(opts map[string]string, keys ...string) error {
+	var missing []string
+	for _, k := range keys {
+		if opts[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required option(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: notifyTimeout}
+}
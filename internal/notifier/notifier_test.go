@@ -0,0 +1,331 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+var testItems = []*parser.Item{
+	{GUID: "1", Title: "First post", Link: "https://example.com/1", Description: "hello", Image: "https://example.com/1.png"},
+	{GUID: "2", Title: "第二篇", Link: "https://example.com/2", Summary: "AI 总结"},
+}
+
+type capturedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   string
+}
+
+func newCaptureServer(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, capturedRequest{r.Method, r.URL.Path, r.URL.Query(), r.Header.Clone(), string(body)})
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), reqs...)
+	}
+}
+
+func TestBackends(t *testing.T) {
+	tests := []struct {
+		kind  string
+		opts  func(url string) map[string]string
+		check func(t *testing.T, r capturedRequest)
+	}{
+		{
+			kind: "bark",
+			opts: func(u string) map[string]string { return map[string]string{"server": u, "device_key": "key"} },
+			check: func(t *testing.T, r capturedRequest) {
+				if !strings.HasPrefix(r.Path, "/key/") {
+					t.Errorf("path = %s", r.Path)
+				}
+				if strings.Contains(r.Path, "] First post/") && r.Query.Get("image") != "https://example.com/1.png" {
+					t.Errorf("image = %q", r.Query.Get("image"))
+				}
+			},
+		},
+		{
+			kind: "telegram",
+			opts: func(u string) map[string]string {
+				return map[string]string{"api_base": u, "bot_token": "tok", "chat_id": "42"}
+			},
+			check: func(t *testing.T, r capturedRequest) {
+				if r.Path != "/bottok/sendMessage" {
+					t.Errorf("path = %s", r.Path)
+				}
+				var p map[string]any
+				json.Unmarshal([]byte(r.Body), &p)
+				if p["chat_id"] != "42" || !strings.Contains(p["text"].(string), "[Blog]") {
+					t.Errorf("payload = %v", p)
+				}
+			},
+		},
+		{
+			kind: "slack",
+			opts: func(u string) map[string]string { return map[string]string{"webhook_url": u + "/hook"} },
+			check: func(t *testing.T, r capturedRequest) {
+				var p map[string]string
+				json.Unmarshal([]byte(r.Body), &p)
+				if !strings.Contains(p["text"], "[Blog]") {
+					t.Errorf("payload = %v", p)
+				}
+			},
+		},
+		{
+			kind: "discord",
+			opts: func(u string) map[string]string { return map[string]string{"webhook_url": u + "/hook"} },
+			check: func(t *testing.T, r capturedRequest) {
+				var p map[string]string
+				json.Unmarshal([]byte(r.Body), &p)
+				if !strings.Contains(p["content"], "[Blog]") {
+					t.Errorf("payload = %v", p)
+				}
+			},
+		},
+		{
+			kind: "ntfy",
+			opts: func(u string) map[string]string { return map[string]string{"server": u, "topic": "news"} },
+			check: func(t *testing.T, r capturedRequest) {
+				if r.Path != "/news" || r.Header.Get("Title") == "" {
+					t.Errorf("path = %s, title = %q", r.Path, r.Header.Get("Title"))
+				}
+			},
+		},
+		{
+			kind: "gotify",
+			opts: func(u string) map[string]string { return map[string]string{"server": u, "token": "app"} },
+			check: func(t *testing.T, r capturedRequest) {
+				if r.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app" {
+					t.Errorf("path = %s, key = %q", r.Path, r.Header.Get("X-Gotify-Key"))
+				}
+			},
+		},
+		{
+			kind: "webhook",
+			opts: func(u string) map[string]string {
+				return map[string]string{"url": u + "/in", "header_X-Token": "secret"}
+			},
+			check: func(t *testing.T, r capturedRequest) {
+				if r.Header.Get("X-Token") != "secret" {
+					t.Errorf("X-Token = %q", r.Header.Get("X-Token"))
+				}
+				var p WebhookPayload
+				if err := json.Unmarshal([]byte(r.Body), &p); err != nil {
+					t.Fatalf("invalid payload: %v", err)
+				}
+				if p.Feed != "Blog" || len(p.Items) == 0 {
+					t.Errorf("payload = %+v", p)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			srv, requests := newCaptureServer(t)
+			n, err := New(tt.kind, tt.opts(srv.URL))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if err := n.Notify("Blog", testItems); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if err := n.NotifyAggregate("Blog", testItems); err != nil {
+				t.Fatalf("NotifyAggregate() error = %v", err)
+			}
+
+			reqs := requests()
+			if len(reqs) == 0 {
+				t.Fatal("no requests received")
+			}
+			for _, r := range reqs {
+				tt.check(t, r)
+			}
+		})
+	}
+}
+
+func TestKinds_MatchConfig(t *testing.T) {
+	if got, want := strings.Join(Kinds(), ","), strings.Join(config.ChannelTypes, ","); got != want {
+		t.Errorf("Kinds() = %s, config.ChannelTypes = %s", got, want)
+	}
+}
+
+func TestBackends_MissingOptions(t *testing.T) {
+	for _, kind := range []string{"telegram", "slack", "discord", "ntfy", "gotify", "email", "webhook"} {
+		if _, err := New(kind, map[string]string{}); err == nil {
+			t.Errorf("New(%q) with no options: expected error", kind)
+		}
+	}
+	if _, err := New("pigeon", nil); err == nil {
+		t.Error("New(pigeon): expected error for unknown type")
+	}
+}
+
+func TestEmail(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	orig := smtpSendMail
+	smtpSendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+	defer func() { smtpSendMail = orig }()
+
+	n, err := New("email", map[string]string{
+		"host": "smtp.example.com",
+		"from": "watcher@example.com",
+		"to":   "a@example.com, b@example.com",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := n.Notify("Blog", testItems[:1]); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if gotAddr != "smtp.example.com:587" || gotFrom != "watcher@example.com" || len(gotTo) != 2 {
+		t.Errorf("addr = %s, from = %s, to = %v", gotAddr, gotFrom, gotTo)
+	}
+	if !strings.Contains(string(gotMsg), "Subject: [Blog] First post") {
+		t.Errorf("message missing subject:\n%s", gotMsg)
+	}
+}
+
+type fakeNotifier struct {
+	err   error
+	calls int
+}
+
+func (f *fakeNotifier) Notify(string, []*parser.Item) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeNotifier) NotifyAggregate(string, []*parser.Item) error {
+	f.calls++
+	return f.err
+}
+
+func TestMulti(t *testing.T) {
+	failing := &fakeNotifier{err: errors.New("boom")}
+	ok := &fakeNotifier{}
+	m := Multi{{Name: "a", Kind: "test-fail", Notifier: failing}, {Name: "b", Kind: "test-ok", Notifier: ok}}
+
+	err := m.Notify("Blog", testItems)
+	if err == nil || !strings.Contains(err.Error(), "a: boom") {
+		t.Errorf("Notify() error = %v, want a: boom", err)
+	}
+	if ok.calls != 1 {
+		t.Errorf("second channel called %d times, want 1", ok.calls)
+	}
+
+	if got := metrics.NotificationFailures.Value("test-fail"); got != 1 {
+		t.Errorf("failures{test-fail} = %v, want 1", got)
+	}
+	if got := metrics.NotificationsSent.Value("test-ok"); got != 1 {
+		t.Errorf("sent{test-ok} = %v, want 1", got)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	var buf strings.Builder
+	p := NewPrinter(&buf, &sync.Mutex{}, "phone")
+
+	if err := p.Notify("Blog", testItems[:1]); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if err := p.NotifyAggregate("Blog", testItems); err != nil {
+		t.Fatalf("NotifyAggregate() error = %v", err)
+	}
+
+	want := `[dry-run] phone -> [Blog] First post
+    hello
+    https://example.com/1
+[dry-run] phone -> [Blog] 2 new items
+    First post
+    第二篇
+`
+	if buf.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestFormatItem_Media(t *testing.T) {
+	item := &parser.Item{
+		Title:       "Episode 7",
+		Description: "show notes",
+		Duration:    42*time.Minute + 10*time.Second,
+		Enclosures:  []parser.Enclosure{{URL: "https://example.com/7.mp3", Type: "audio/mpeg", Length: 36910694}},
+	}
+	// 非媒体模式的订阅源不显示附件信息
+	if got := formatItem("Pod", item).Body; got != "show notes" {
+		t.Errorf("Body without media mode = %q", got)
+	}
+	item.Media = true
+	if got := formatItem("Pod", item).Body; got != "42:10 · 35.2 MB\nshow notes" {
+		t.Errorf("Body = %q", got)
+	}
+
+	item.Duration = 2*time.Hour + 5*time.Second
+	item.Enclosures[0].Length = 0
+	if got := mediaInfo(item); got != "2:00:05" {
+		t.Errorf("mediaInfo() = %q", got)
+	}
+}
+
+func TestAlert(t *testing.T) {
+	var buf strings.Builder
+	p := NewPrinter(&buf, &sync.Mutex{}, "phone")
+
+	if n, err := Alert(p, "Blog", "Feed failing: 3 consecutive failures", "fetch: timeout", "https://example.com/feed"); n != 1 || err != nil {
+		t.Fatalf("Alert() = %d, %v", n, err)
+	}
+
+	want := `[dry-run] phone -> [Blog] Feed failing: 3 consecutive failures
+    fetch: timeout
+    https://example.com/feed
+`
+	if buf.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestAlert_PartialFailure(t *testing.T) {
+	ok := &fakeNotifier{}
+	m := Multi{{Name: "a", Notifier: &fakeNotifier{err: errors.New("boom")}}, {Name: "b", Notifier: ok}}
+
+	n, err := Alert(m, "Blog", "Feed failing", "fetch: timeout", "")
+	if n != 1 || err == nil || !strings.Contains(err.Error(), "a: boom") {
+		t.Errorf("Alert() = %d, %v, want 1, a: boom", n, err)
+	}
+	if ok.calls != 1 {
+		t.Errorf("second channel called %d times, want 1", ok.calls)
+	}
+}
@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const defaultNtfyServer = "https://ntfy.sh"
+
+// ntfySender 通过 ntfy 的 HTTP 发布接口发送消息
+type ntfySender struct {
+	server string
+	topic  string
+	token  string
+	client *http.Client
+}
+
+func init() {
+	Register("ntfy", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "topic"); err != nil {
+			return nil, err
+		}
+		server := opts["server"]
+		if server == "" {
+			server = defaultNtfyServer
+		}
+		return messageNotifier{&ntfySender{
+			server: strings.TrimRight(server, "/"),
+			topic:  opts["topic"],
+			token:  opts["token"],
+			client: newHTTPClient(),
+		}}, nil
+	})
+}
+
+func (n *ntfySender) send(m message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.server+"/"+n.topic, strings.NewReader(m.Body))
+	if err != nil {
+		return err
+	}
+
+	// ntfy 的 Title 头要求是 ASCII，非 ASCII 内容使用 RFC 2047 编码
+	req.Header.Set("Title", encodeHeader(m.Title))
+	if m.URL != "" {
+		req.Header.Set("Click", m.URL)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return do(n.client, req)
+}
@@ -0,0 +1,37 @@
+package notifier
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// printer 把通知内容打印出来而不发送，用于 --dry-run
+type printer struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	channel string
+}
+
+// NewPrinter 返回把通知打印到 w 的 Notifier，channel 为被替代的渠道名。
+// 共用同一个 w 的 printer 应共用 mu，避免并发输出交错。
+func NewPrinter(w io.Writer, mu *sync.Mutex, channel string) Notifier {
+	return messageNotifier{printer{mu: mu, w: w, channel: channel}}
+}
+
+func (p printer) send(m message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[dry-run] %s -> %s\n", p.channel, m.Title)
+	for _, line := range strings.Split(m.Body, "\n") {
+		fmt.Fprintf(&b, "    %s\n", line)
+	}
+	if m.URL != "" {
+		fmt.Fprintf(&b, "    %s\n", m.URL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
@@ -0,0 +1,29 @@
+package notifier
+
+import "net/http"
+
+// slackSender 通过 Slack Incoming Webhook 发送消息
+type slackSender struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func init() {
+	Register("slack", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "webhook_url"); err != nil {
+			return nil, err
+		}
+		return messageNotifier{&slackSender{
+			webhookURL: opts["webhook_url"],
+			client:     newHTTPClient(),
+		}}, nil
+	})
+}
+
+func (s *slackSender) send(m message) error {
+	title := "*" + m.Title + "*"
+	if m.URL != "" {
+		title = "*<" + m.URL + "|" + m.Title + ">*"
+	}
+	return postJSON(s.client, s.webhookURL, map[string]string{"text": title + "\n" + m.Body}, nil)
+}
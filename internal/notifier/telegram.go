@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultTelegramAPI = "https://api.telegram.org"
+
+// telegramSender 通过 Telegram Bot API 的 sendMessage 发送消息
+type telegramSender struct {
+	apiBase  string
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+func init() {
+	Register("telegram", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "bot_token", "chat_id"); err != nil {
+			return nil, err
+		}
+		apiBase := opts["api_base"]
+		if apiBase == "" {
+			apiBase = defaultTelegramAPI
+		}
+		return messageNotifier{&telegramSender{
+			apiBase:  strings.TrimRight(apiBase, "/"),
+			botToken: opts["bot_token"],
+			chatID:   opts["chat_id"],
+			client:   newHTTPClient(),
+		}}, nil
+	})
+}
+
+func (t *telegramSender) send(m message) error {
+	text := m.Title + "\n" + m.Body
+	if m.URL != "" {
+		text += "\n" + m.URL
+	}
+
+	payload := map[string]any{
+		"chat_id":                  t.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+
+	return postJSON(t.client, fmt.Sprintf("%s/bot%s/sendMessage", t.apiBase, t.botToken), payload, nil)
+}
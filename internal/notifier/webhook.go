@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+// WebhookPayload 是通用 JSON webhook 发送的请求体
+type WebhookPayload struct {
+	Feed      string        `json:"feed"`
+	Aggregate bool          `json:"aggregate"`
+	Items     []WebhookItem `json:"items"`
+}
+
+type WebhookItem struct {
+	GUID            string             `json:"guid,omitempty"`
+	Title           string             `json:"title"`
+	Link            string             `json:"link,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	DescriptionHTML string             `json:"description_html,omitempty"` // 订阅源中的原始 HTML 描述
+	Summary         string             `json:"summary,omitempty"`
+	Published       string             `json:"published,omitempty"` // RFC 3339
+	Updated         string             `json:"updated,omitempty"`
+	Authors         []string           `json:"authors,omitempty"`
+	Categories      []string           `json:"categories,omitempty"`
+	Enclosures      []parser.Enclosure `json:"enclosures,omitempty"`
+	Image           string             `json:"image,omitempty"`
+}
+
+// WebhookNotifier 把条目以 JSON 形式 POST 到任意 URL
+type WebhookNotifier struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+func init() {
+	Register("webhook", func(opts map[string]string) (Notifier, error) {
+		if err := required(opts, "url"); err != nil {
+			return nil, err
+		}
+		// header_<Name> 形式的配置项作为请求头发送
+		header := http.Header{}
+		for k, v := range opts {
+			if name, ok := strings.CutPrefix(k, "header_"); ok {
+				header.Set(name, v)
+			}
+		}
+		return &WebhookNotifier{
+			url:    opts["url"],
+			header: header,
+			client: newHTTPClient(),
+		}, nil
+	})
+}
+
+func (w *WebhookNotifier) Notify(feedName string, items []*parser.Item) error {
+	return w.post(feedName, items, false)
+}
+
+func (w *WebhookNotifier) NotifyAggregate(feedName string, items []*parser.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return w.post(feedName, items, true)
+}
+
+func (w *WebhookNotifier) post(feedName string, items []*parser.Item, aggregate bool) error {
+	payload := WebhookPayload{
+		Feed:      feedName,
+		Aggregate: aggregate,
+		Items:     make([]WebhookItem, 0, len(items)),
+	}
+	for _, item := range items {
+		payload.Items = append(payload.Items, WebhookItem{
+			GUID:            item.GUID,
+			Title:           item.Title,
+			Link:            item.Link,
+			Description:     item.Description,
+			DescriptionHTML: item.DescriptionHTML,
+			Summary:         item.Summary,
+			Published:       formatTime(item.Published),
+			Updated:         formatTime(item.Updated),
+			Authors:         item.Authors,
+			Categories:      item.Categories,
+			Enclosures:      item.Enclosures,
+			Image:           item.Image,
+		})
+	}
+	return postJSON(w.client, w.url, payload, w.header)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,250 @@
+package opml
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+)
+
+// Document 是 OPML 2.0 文档
+type Document struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Head    Head      `xml:"head"`
+	Body    []Outline `xml:"body>outline"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Entry 是从 OPML 中展开的一个订阅源
+type Entry struct {
+	Title    string
+	URL      string
+	Category string // 以 "/" 分隔的分类路径，分类名中的 "/" 和 "\" 前加 "\" 转义
+}
+
+// Rejected 是因无效而没有导入的订阅源
+type Rejected struct {
+	Entry Entry
+	Err   error
+}
+
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+	dec := xml.NewDecoder(r)
+	// 部分阅读器导出的 OPML 声明了非 UTF-8 编码，这里按原样读取
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML: %w", err)
+	}
+	return &doc, nil
+}
+
+// Entries 展开嵌套的 outline，没有 xmlUrl 的 outline 视为分类
+func (d *Document) Entries() []Entry {
+	var entries []Entry
+	var walk func(outlines []Outline, category []string)
+	walk = func(outlines []Outline, category []string) {
+		for _, o := range outlines {
+			title := o.Title
+			if title == "" {
+				title = o.Text
+			}
+			if o.XMLURL != "" {
+				entries = append(entries, Entry{
+					Title:    strings.TrimSpace(title),
+					URL:      strings.TrimSpace(o.XMLURL),
+					Category: strings.Join(category, "/"),
+				})
+				continue
+			}
+			walk(o.Outlines, append(category[:len(category):len(category)], escapeCategory(strings.TrimSpace(title))))
+		}
+	}
+	walk(d.Body, nil)
+	return entries
+}
+
+// Merge 返回 entries 中尚未出现在 cfg 里的订阅源（按 URL 判断），
+// 已有订阅源的配置保持不变。新订阅源的 ID 由标题或 URL 生成，
+// 对同一输入总是相同。没有通过 config.ValidateFeed 检查的条目不导入，
+// 作为 rejected 返回。
+func Merge(cfg *config.Config, entries []Entry) (added []config.Feed, rejected []Rejected) {
+	urls := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, f := range cfg.Feeds {
+		urls[normalizeURL(f.URL)] = true
+		if f.Site != "" {
+			urls[normalizeURL(f.Site)] = true
+		}
+		ids[f.ID] = true
+	}
+
+	for _, e := range entries {
+		key := normalizeURL(e.URL)
+		if urls[key] {
+			continue
+		}
+
+		id := feedID(e)
+		if ids[id] {
+			id = id + "-" + shortHash(e.URL)
+		}
+
+		name := e.Title
+		if name == "" {
+			name = id
+		}
+		feed := config.Feed{
+			ID:       id,
+			Name:     name,
+			URL:      e.URL,
+			Notify:   true,
+			Category: e.Category,
+		}
+		if err := config.ValidateFeed(feed); err != nil {
+			rejected = append(rejected, Rejected{Entry: e, Err: err})
+			continue
+		}
+		urls[key] = true
+		ids[id] = true
+		added = append(added, feed)
+	}
+	return added, rejected
+}
+
+// Export 以 OPML 2.0 写出配置中的订阅源，分类路径还原为嵌套的 outline
+func Export(w io.Writer, cfg *config.Config, now time.Time) error {
+	doc := Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       "RSS Watcher feeds",
+			DateCreated: now.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, f := range cfg.Feeds {
+		siblings := &doc.Body
+		if f.Category != "" {
+			for _, name := range splitCategory(f.Category) {
+				siblings = &findOrAdd(siblings, name).Outlines
+			}
+		}
+		// 只配置了网站地址的订阅源以网站地址代替 xmlUrl，导入时可再次自动发现
+		xmlURL := f.URL
+		if xmlURL == "" {
+			xmlURL = f.Site
+		}
+		*siblings = append(*siblings, Outline{
+			Text:    f.Name,
+			Title:   f.Name,
+			Type:    "rss",
+			XMLURL:  xmlURL,
+			HTMLURL: f.Site,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func findOrAdd(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		o := &(*outlines)[i]
+		if o.XMLURL == "" && o.Text == name {
+			return o
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+// escapeCategory 在分类名中的 "\" 和 "/" 前加 "\"，使其可以放进分类路径
+func escapeCategory(name string) string {
+	return strings.NewReplacer(`\`, `\\`, "/", `\/`).Replace(name)
+}
+
+// splitCategory 按未转义的 "/" 拆分分类路径并还原转义，是 escapeCategory 的逆操作
+func splitCategory(category string) []string {
+	var names []string
+	var b strings.Builder
+	for i := 0; i < len(category); i++ {
+		switch c := category[i]; {
+		case c == '\\' && i+1 < len(category):
+			i++
+			b.WriteByte(category[i])
+		case c == '/':
+			names = append(names, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return append(names, b.String())
+}
+
+// feedID 优先用标题生成 ID；标题中没有 ASCII 字母数字（如中文标题）时改用 URL
+func feedID(e Entry) string {
+	if id := slug(e.Title); id != "" {
+		return id
+	}
+	if u, err := url.Parse(e.URL); err == nil && u.Host != "" {
+		if id := slug(strings.TrimPrefix(u.Host, "www.") + " " + u.Path); id != "" {
+			return id
+		}
+	}
+	return "feed-" + shortHash(e.URL)
+}
+
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if b.Len() > 0 && !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func shortHash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])[:6]
+}
+
+func normalizeURL(s string) string {
+	return strings.TrimSuffix(strings.TrimSpace(s), "/")
+}
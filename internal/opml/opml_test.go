@@ -0,0 +1,146 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/config"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Tech">
+      <outline text="Go">
+        <outline type="rss" text="The Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+      </outline>
+      <outline type="rss" text="阮一峰的网络日志" xmlUrl="https://www.ruanyifeng.com/blog/atom.xml"/>
+    </outline>
+    <outline type="rss" text="Existing" xmlUrl="https://example.com/rss/"/>
+    <outline type="rss" title="Existing" text="Existing" xmlUrl="https://other.example.com/rss"/>
+  </body>
+</opml>`
+
+func TestEntries(t *testing.T) {
+	doc, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	entries := doc.Entries()
+	want := []Entry{
+		{Title: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Tech/Go"},
+		{Title: "阮一峰的网络日志", URL: "https://www.ruanyifeng.com/blog/atom.xml", Category: "Tech"},
+		{Title: "Existing", URL: "https://example.com/rss/"},
+		{Title: "Existing", URL: "https://other.example.com/rss"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Entries() = %+v", entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Entries()[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	doc, _ := Parse(strings.NewReader(sample))
+	cfg := &config.Config{Feeds: []config.Feed{
+		{ID: "existing", Name: "Mine", URL: "https://example.com/rss", Notify: false},
+	}}
+
+	added, rejected := Merge(cfg, doc.Entries())
+	if len(rejected) != 0 {
+		t.Errorf("Merge() rejected = %+v", rejected)
+	}
+	ids := make([]string, len(added))
+	for i, f := range added {
+		ids[i] = f.ID
+	}
+
+	// 已有 URL 被跳过；中文标题使用 URL 生成 ID；ID 冲突时追加哈希后缀
+	want := []string{"the-go-blog", "ruanyifeng-com-blog-atom-xml", "existing-" + shortHash("https://other.example.com/rss")}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Merge() ids = %v, want %v", ids, want)
+	}
+	if added[0].Category != "Tech/Go" || !added[0].Notify {
+		t.Errorf("Merge()[0] = %+v", added[0])
+	}
+
+	// 再次合并结果相同
+	again, _ := Merge(cfg, doc.Entries())
+	for i := range added {
+		if again[i].ID != added[i].ID {
+			t.Errorf("Merge() not stable: %s != %s", again[i].ID, added[i].ID)
+		}
+	}
+}
+
+func TestMerge_RejectsInvalid(t *testing.T) {
+	cfg := &config.Config{}
+	entries := []Entry{
+		{Title: "Relative", URL: "/feed.xml"},
+		{Title: "FTP", URL: "ftp://example.com/feed.xml"},
+		{Title: "Script", URL: "javascript:alert(1)"},
+		{Title: "Good", URL: "https://example.com/feed.xml"},
+		// 无效条目不占用 URL 和 ID
+		{Title: "Relative", URL: "https://example.com/relative.xml"},
+	}
+
+	added, rejected := Merge(cfg, entries)
+	if len(rejected) != 3 {
+		t.Fatalf("Merge() rejected = %+v, want 3 entries", rejected)
+	}
+	for _, r := range rejected {
+		if r.Err == nil || !strings.Contains(r.Err.Error(), "url") {
+			t.Errorf("rejected %s: err = %v", r.Entry.URL, r.Err)
+		}
+	}
+	if len(added) != 2 || added[0].ID != "good" || added[1].ID != "relative" {
+		t.Errorf("Merge() added = %+v", added)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	cfg := &config.Config{Feeds: []config.Feed{
+		{ID: "a", Name: "A", URL: "https://a.example.com/feed", Category: "Tech/Go"},
+		{ID: "b", Name: "B", URL: "https://b.example.com/feed"},
+		{ID: "c", Name: "C", URL: "https://c.example.com/feed", Category: "Tech"},
+		{ID: "d", Name: "D", URL: "https://d.example.com/feed", Category: "Tech/Go"},
+		{ID: "e", Name: "E", URL: "https://e.example.com/feed", Category: `Tech/AI\/ML`},
+		{ID: "f", Name: "F", URL: "https://f.example.com/feed", Category: `C:\\Windows`},
+	}}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, cfg, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<opml version="2.0">`) {
+		t.Errorf("missing OPML 2.0 header:\n%s", out)
+	}
+	if !strings.Contains(out, `text="AI/ML"`) {
+		t.Errorf("escaped slash not restored in outline name:\n%s", out)
+	}
+
+	doc, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, e := range doc.Entries() {
+		got[e.URL] = e.Category
+	}
+	for _, f := range cfg.Feeds {
+		if category, ok := got[f.URL]; !ok || category != f.Category {
+			t.Errorf("%s: category = %q, want %q", f.URL, category, f.Category)
+		}
+	}
+	if len(doc.Body) != 3 {
+		t.Errorf("top-level outlines = %d, want 3 (Tech and C:\\Windows groups and B)", len(doc.Body))
+	}
+}
@@ -0,0 +1,155 @@
+package output
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Generator string      `xml:"generator,omitempty"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// writeAtom 输出 Atom 1.0 文档。条目的 updated 为加入输出的时间，
+// category 为来源订阅源的名称。
+func writeAtom(w io.Writer, opts Options, entries []Entry, now time.Time) error {
+	feed := atomFeed{
+		ID:        feedID(opts),
+		Title:     opts.Title,
+		Updated:   formatTime(now),
+		Author:    &atomPerson{Name: opts.Title},
+		Generator: "rsswatcher",
+	}
+	if opts.Link != "" {
+		feed.Links = []atomLink{{Href: opts.Link, Rel: "self"}}
+	}
+
+	for _, e := range entries {
+		ae := atomEntry{
+			ID:      e.ID,
+			Title:   e.Title,
+			Updated: formatTime(e.Added),
+			Summary: e.Summary,
+		}
+		if e.Link != "" {
+			ae.Links = []atomLink{{Href: e.Link, Rel: "alternate"}}
+		}
+		if !e.Published.IsZero() {
+			ae.Published = formatTime(e.Published)
+		}
+		for _, name := range e.Authors {
+			ae.Authors = append(ae.Authors, atomPerson{Name: name})
+		}
+		if e.FeedName != "" {
+			ae.Categories = []atomCategory{{Term: e.FeedName}}
+		}
+		if e.Content != "" {
+			ae.Content = &atomText{Type: "text", Body: e.Content}
+			if e.ContentHTML {
+				ae.Content.Type = "html"
+			}
+		}
+		feed.Entries = append(feed.Entries, ae)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// readAtom 读取 writeAtom 输出的条目
+func readAtom(r io.Reader) ([]Entry, error) {
+	var feed atomFeed
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(feed.Entries))
+	for _, ae := range feed.Entries {
+		e := Entry{ID: ae.ID, Title: ae.Title, Summary: ae.Summary}
+		for _, l := range ae.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				e.Link = l.Href
+				break
+			}
+		}
+		for _, a := range ae.Authors {
+			e.Authors = append(e.Authors, a.Name)
+		}
+		if len(ae.Categories) > 0 {
+			e.FeedName = ae.Categories[0].Term
+		}
+		if ae.Content != nil {
+			e.Content = ae.Content.Body
+			e.ContentHTML = ae.Content.Type == "html"
+		}
+		e.Published, _ = parseTime(ae.Published)
+		e.Added, _ = parseTime(ae.Updated)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// feedID 返回输出订阅源的 ID，优先使用其公开地址
+func feedID(opts Options) string {
+	if opts.Link != "" {
+		return opts.Link
+	}
+	return "urn:rsswatcher:output"
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseTime 解析 RFC 3339 时间，为空时返回零值
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonFeedVersion 是输出的 JSON Feed 版本
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version string     `json:"version"`
+	Title   string     `json:"title"`
+	FeedURL string     `json:"feed_url,omitempty"`
+	Items   []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	Summary       string       `json:"summary,omitempty"`
+	ContentHTML   string       `json:"content_html,omitempty"`
+	ContentText   string       `json:"content_text,omitempty"`
+	DatePublished string       `json:"date_published,omitempty"`
+	DateModified  string       `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+// writeJSONFeed 输出 JSON Feed 1.1 文档。条目的 date_modified 为加入输出的时间，
+// tags 为来源订阅源的名称。
+func writeJSONFeed(w io.Writer, opts Options, entries []Entry) error {
+	feed := jsonFeed{
+		Version: jsonFeedVersion,
+		Title:   opts.Title,
+		FeedURL: opts.Link,
+		Items:   make([]jsonItem, 0, len(entries)),
+	}
+
+	for _, e := range entries {
+		item := jsonItem{
+			ID:           e.ID,
+			URL:          e.Link,
+			Title:        e.Title,
+			Summary:      e.Summary,
+			DateModified: formatTime(e.Added),
+		}
+		// JSON Feed 要求条目至少有一种内容
+		switch {
+		case e.ContentHTML:
+			item.ContentHTML = e.Content
+		case e.Content != "":
+			item.ContentText = e.Content
+		case e.Summary != "":
+			item.ContentText = e.Summary
+		default:
+			item.ContentText = e.Title
+		}
+		if !e.Published.IsZero() {
+			item.DatePublished = formatTime(e.Published)
+		}
+		for _, name := range e.Authors {
+			item.Authors = append(item.Authors, jsonAuthor{Name: name})
+		}
+		if e.FeedName != "" {
+			item.Tags = []string{e.FeedName}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(feed)
+}
+
+// readJSONFeed 读取 writeJSONFeed 输出的条目
+func readJSONFeed(r io.Reader) ([]Entry, error) {
+	var feed jsonFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		e := Entry{ID: item.ID, Title: item.Title, Link: item.URL, Summary: item.Summary}
+		if item.ContentHTML != "" {
+			e.Content, e.ContentHTML = item.ContentHTML, true
+		} else {
+			e.Content = item.ContentText
+		}
+		for _, a := range item.Authors {
+			e.Authors = append(e.Authors, a.Name)
+		}
+		if len(item.Tags) > 0 {
+			e.FeedName = item.Tags[0]
+		}
+		e.Published, _ = parseTime(item.DatePublished)
+		e.Added, _ = parseTime(item.DateModified)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
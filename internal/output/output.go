@@ -0,0 +1,221 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/deduper"
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+// 输出格式
+const (
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// 条目排序方式
+const (
+	OrderPublished = "published" // 按发布时间，没有发布时间的按加入时间
+	OrderAdded     = "added"     // 按加入输出的时间
+)
+
+// Options 是合并输出订阅源的设置
+type Options struct {
+	Path     string
+	Format   string
+	Title    string
+	Link     string // 输出订阅源自身的公开地址，可为空
+	MaxItems int
+	Order    string
+}
+
+// Entry 是输出订阅源中的一个条目
+type Entry struct {
+	ID       string
+	FeedName string
+	Title    string
+	Link     string
+	Authors  []string
+	// Summary 是 AI 总结，没有总结时为条目描述
+	Summary string
+	Content string
+	// ContentHTML 表示 Content 为 HTML，否则为纯文本
+	ContentHTML bool
+	Published   time.Time // 未知时为零值
+	Added       time.Time
+}
+
+// Writer 收集各订阅源的新条目，与已输出的条目合并后写入文件
+type Writer struct {
+	opts Options
+
+	mu    sync.Mutex
+	added []Entry
+}
+
+func New(opts Options) *Writer {
+	return &Writer{opts: opts}
+}
+
+// Add 加入 feedID 的新条目，Flush 时写入文件。dedupeKey 是订阅源的去重方式，
+// 用于生成与去重一致的条目 ID
+func (w *Writer) Add(feedID, feedName, dedupeKey string, items []*parser.Item, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, item := range items {
+		w.added = append(w.added, newEntry(feedID, feedName, deduper.ItemKey(item, dedupeKey), item, now))
+	}
+}
+
+// newEntry 把条目转换为输出条目。HTML 内容经过白名单过滤，
+// 过滤后为空时改用纯文本描述
+func newEntry(feedID, feedName, key string, item *parser.Item, now time.Time) Entry {
+	e := Entry{
+		ID:        entryID(feedID, key),
+		FeedName:  feedName,
+		Title:     item.Title,
+		Link:      item.Link,
+		Authors:   item.Authors,
+		Summary:   item.Summary,
+		Published: item.Published,
+		Added:     now,
+	}
+	if e.Summary == "" {
+		e.Summary = item.Description
+	}
+	content := item.Content
+	if content == "" {
+		content = item.DescriptionHTML
+	}
+	if content = sanitize(content); content != "" {
+		e.Content, e.ContentHTML = content, true
+	} else {
+		e.Content = item.Description
+	}
+	return e
+}
+
+// entryID 根据订阅源和条目的去重 key 生成稳定的条目 ID
+func entryID(feedID, key string) string {
+	sum := sha1.Sum([]byte(key))
+	return fmt.Sprintf("urn:rsswatcher:%s:%s", feedID, hex.EncodeToString(sum[:8]))
+}
+
+// Flush 把新加入的条目与文件中已有的条目合并，排序并截断后重写文件，
+// 返回写入的条目数。没有新条目时不写文件。
+func (w *Writer) Flush(now time.Time) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.added) == 0 {
+		return 0, nil
+	}
+
+	existing, err := w.read()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", w.opts.Path, err)
+	}
+	// 旧版本写入的文件中可能有未经过滤的 HTML
+	for i := range existing {
+		if existing[i].ContentHTML {
+			existing[i].Content = sanitize(existing[i].Content)
+		}
+	}
+	entries := merge(existing, w.added, w.opts.Order, w.opts.MaxItems)
+
+	if err := os.MkdirAll(filepath.Dir(w.opts.Path), 0755); err != nil {
+		return 0, err
+	}
+	tmpPath := w.opts.Path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	if w.opts.Format == FormatJSON {
+		err = writeJSONFeed(f, w.opts, entries)
+	} else {
+		err = writeAtom(f, w.opts, entries, now)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, w.opts.Path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	w.added = nil
+	return len(entries), nil
+}
+
+// read 读取已输出的条目，文件不存在时返回空
+func (w *Writer) read() ([]Entry, error) {
+	f, err := os.Open(w.opts.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if w.opts.Format == FormatJSON {
+		return readJSONFeed(r)
+	}
+	return readAtom(r)
+}
+
+// merge 合并已有条目和新条目，同一条目以新内容为准但保留最初的加入时间，
+// 按 order 从新到旧排序后最多保留 max 个，max <= 0 时不限制
+func merge(existing, added []Entry, order string, max int) []Entry {
+	byID := make(map[string]int, len(existing)+len(added))
+	entries := make([]Entry, 0, len(existing)+len(added))
+	for _, e := range append(existing, added...) {
+		if i, ok := byID[e.ID]; ok {
+			e.Added = entries[i].Added
+			entries[i] = e
+			continue
+		}
+		byID[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if order == OrderAdded {
+			if !a.Added.Equal(b.Added) {
+				return a.Added.After(b.Added)
+			}
+			return a.published().After(b.published())
+		}
+		if !a.published().Equal(b.published()) {
+			return a.published().After(b.published())
+		}
+		return a.Added.After(b.Added)
+	})
+
+	if max > 0 && len(entries) > max {
+		entries = entries[:max]
+	}
+	return entries
+}
+
+// published 返回用于排序的发布时间，未知时为加入时间
+func (e Entry) published() time.Time {
+	if e.Published.IsZero() {
+		return e.Added
+	}
+	return e.Published
+}
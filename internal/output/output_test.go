@@ -0,0 +1,222 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
+)
+
+var (
+	t0 = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	t1 = t0.Add(time.Hour)
+)
+
+func titles(entries []Entry) string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Title
+	}
+	return strings.Join(out, ",")
+}
+
+func TestWriter_Flush(t *testing.T) {
+	for _, format := range []string{FormatAtom, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out", "feed")
+			w := New(Options{Path: path, Format: format, Title: "Curated", Link: "https://example.com/feed", MaxItems: 3, Order: OrderPublished})
+
+			if n, err := w.Flush(t0); err != nil || n != 0 {
+				t.Fatalf("Flush() with nothing added = %d, %v", n, err)
+			}
+			if _, err := os.Stat(path); err == nil {
+				t.Fatal("Flush() with nothing added wrote a file")
+			}
+
+			w.Add("blog", "Blog", "", []*parser.Item{
+				{GUID: "1", Title: "Old", Link: "https://example.com/1", Published: t0.Add(-48 * time.Hour), Summary: "AI summary", Description: "desc"},
+				{GUID: "2", Title: "Newer", Published: t0.Add(-time.Hour), Content: "<p>full</p>", Authors: []string{"Ann"}},
+			}, t0)
+			if n, err := w.Flush(t0); err != nil || n != 2 {
+				t.Fatalf("Flush() = %d, %v", n, err)
+			}
+
+			// 第二次运行：与文件中的条目合并，只保留最新的 3 个
+			w.Add("news", "News", "", []*parser.Item{
+				{Link: "https://example.com/n", Title: "Undated"},
+				{GUID: "x", Title: "Middle", Published: t0.Add(-24 * time.Hour)},
+			}, t1)
+			if n, err := w.Flush(t1); err != nil || n != 3 {
+				t.Fatalf("Flush() = %d, %v", n, err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var entries []Entry
+			if format == FormatJSON {
+				entries, err = readJSONFeed(f)
+			} else {
+				entries, err = readAtom(f)
+			}
+			if err != nil {
+				t.Fatalf("read error = %v", err)
+			}
+			if got := titles(entries); got != "Undated,Newer,Middle" {
+				t.Errorf("entries = %s", got)
+			}
+
+			newer := entries[1]
+			if newer.FeedName != "Blog" || newer.Content != "<p>full</p>" || !newer.ContentHTML ||
+				len(newer.Authors) != 1 || !newer.Added.Equal(t0) || !newer.Published.Equal(t0.Add(-time.Hour)) {
+				t.Errorf("entry = %+v", newer)
+			}
+			if !entries[0].Published.IsZero() || !entries[0].Added.Equal(t1) {
+				t.Errorf("undated entry = %+v", entries[0])
+			}
+
+			// 输出能被常见的订阅源解析器读取，AI 总结作为条目摘要
+			data, _ := os.ReadFile(path)
+			items, err := parser.New().Parse(data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(items) != 3 {
+				t.Errorf("parsed %d items, want 3", len(items))
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	existing := []Entry{
+		{ID: "a", Title: "A", Added: t0, Published: t0.Add(-time.Hour)},
+		{ID: "b", Title: "B", Added: t0},
+	}
+	added := []Entry{
+		{ID: "a", Title: "A2", Added: t1, Published: t0.Add(-time.Hour)},
+		{ID: "c", Title: "C", Added: t1, Published: t0.Add(-72 * time.Hour)},
+	}
+
+	got := merge(existing, added, OrderPublished, 0)
+	if s := titles(got); s != "B,A2,C" {
+		t.Errorf("published order = %s", s)
+	}
+	// 重复出现的条目保留最初的加入时间
+	if !got[1].Added.Equal(t0) {
+		t.Errorf("A2 added = %v, want %v", got[1].Added, t0)
+	}
+
+	if s := titles(merge(existing, added, OrderAdded, 2)); s != "C,B" {
+		t.Errorf("added order = %s", s)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.xml")
+	w := New(Options{Path: path, Format: FormatAtom, Title: "Curated"})
+	w.Add("blog", "Blog", "", []*parser.Item{
+		{GUID: "1", Title: "With summary", Summary: "AI 总结", Description: "desc"},
+		{GUID: "2", Title: "Without", Description: "plain description"},
+	}, t0)
+	if _, err := w.Flush(t0); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"<summary>AI 总结</summary>", "<summary>plain description</summary>", `<content type="text">desc</content>`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output missing %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestEntryID_DedupeKey(t *testing.T) {
+	a := &parser.Item{GUID: "1", Link: "https://example.com/post"}
+	b := &parser.Item{GUID: "2", Link: "https://example.com/post"}
+
+	// 按链接去重的订阅源中 GUID 变化的条目是同一个条目
+	w := New(Options{})
+	w.Add("blog", "Blog", "link", []*parser.Item{a, b}, t0)
+	if w.added[0].ID != w.added[1].ID {
+		t.Errorf("IDs with dedupe_key link = %s, %s, want equal", w.added[0].ID, w.added[1].ID)
+	}
+
+	w = New(Options{})
+	w.Add("blog", "Blog", "guid", []*parser.Item{a, b}, t0)
+	if w.added[0].ID == w.added[1].ID {
+		t.Errorf("IDs with dedupe_key guid are equal: %s", w.added[0].ID)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"kept", `<p>Hello <a href="https://example.com/" title="x">link</a><br/></p>`, `<p>Hello <a href="https://example.com/" title="x">link</a><br /></p>`},
+		{"script", `<p>a</p><script>alert(1)</script><style>p{}</style>b`, `<p>a</p>b`},
+		{"events", `<img src="https://example.com/a.png" onerror="alert(1)" alt="a">`, `<img src="https://example.com/a.png" alt="a">`},
+		{"javascript url", `<a href=" JavaScript:alert(1)">x</a><a href="&#106;avascript:alert(1)">y</a>`, `<a>x</a><a>y</a>`},
+		{"unknown tags", `<custom onclick="x">text &amp; <b>bold</b></custom>`, `text &amp; <b>bold</b>`},
+		{"iframe", `<iframe src="https://evil.example/"><p>fallback</p></iframe>after`, `after`},
+		{"only script", `<script>alert(1)</script>`, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.in); got != tt.want {
+				t.Errorf("sanitize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_SanitizesHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.json")
+	// 旧版本写入的未过滤内容也会在重写时过滤
+	old := `{"version": "https://jsonfeed.org/version/1.1", "title": "Curated", "items": [
+		{"id": "old", "title": "Old", "content_html": "<p>old</p><script>alert(1)</script>", "date_modified": "2026-09-30T08:00:00Z"}
+	]}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(Options{Path: path, Format: FormatJSON, Title: "Curated"})
+	w.Add("blog", "Blog", "", []*parser.Item{
+		{GUID: "1", Title: "Script", Content: `<p onclick="x()">hi</p><script>alert(1)</script>`},
+		{GUID: "2", Title: "Only script", DescriptionHTML: `<script>alert(1)</script>`, Description: "plain"},
+	}, t0)
+	if _, err := w.Flush(t0); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "<script") || strings.Contains(string(data), "onclick") {
+		t.Errorf("output contains unsafe HTML:\n%s", data)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	entries, err := readJSONFeed(f)
+	if err != nil {
+		t.Fatalf("readJSONFeed() error = %v", err)
+	}
+	got := map[string]Entry{}
+	for _, e := range entries {
+		got[e.Title] = e
+	}
+	if e := got["Script"]; e.Content != "<p>hi</p>" || !e.ContentHTML {
+		t.Errorf("Script entry = %+v", e)
+	}
+	if e := got["Only script"]; e.Content != "plain" || e.ContentHTML {
+		t.Errorf("Only script entry = %+v", e)
+	}
+	if e := got["Old"]; e.Content != "<p>old</p>" {
+		t.Errorf("Old entry = %+v", e)
+	}
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags 是输出中保留的元素及其允许的属性，其他元素只保留文字
+var allowedTags = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.Hr: nil, atom.Div: nil, atom.Span: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.B: nil, atom.Strong: nil, atom.I: nil, atom.Em: nil, atom.U: nil, atom.S: nil,
+	atom.Sub: nil, atom.Sup: nil, atom.Small: nil, atom.Mark: nil,
+	atom.Blockquote: nil, atom.Pre: nil, atom.Code: nil,
+	atom.Ul: nil, atom.Ol: nil, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.Table: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tr: nil,
+	atom.Th: {"colspan", "rowspan"}, atom.Td: {"colspan", "rowspan"},
+	atom.Figure: nil, atom.Figcaption: nil,
+	atom.A:   {"href", "title"},
+	atom.Img: {"src", "alt", "title", "width", "height"},
+}
+
+// droppedTags 是连同内容一起丢弃的元素
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Svg: true,
+	atom.Math: true, atom.Form: true, atom.Textarea: true, atom.Select: true,
+}
+
+// urlAttrs 是值为地址的属性，只允许 http、https、mailto 和相对地址
+var urlAttrs = map[string]bool{"href": true, "src": true}
+
+// sanitize 按白名单过滤订阅源中的 HTML：丢弃脚本等元素、事件处理属性和
+// javascript: 等地址，使合并输出中的内容可以安全地被阅读器显示
+func sanitize(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			t := z.Token()
+			switch {
+			case droppedTags[t.DataAtom]:
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case skip > 0:
+			case tt == html.EndTagToken:
+				if _, ok := allowedTags[t.DataAtom]; ok {
+					b.WriteString("</" + t.Data + ">")
+				}
+			default:
+				if attrs, ok := allowedTags[t.DataAtom]; ok {
+					writeTag(&b, t, attrs)
+				}
+			}
+		}
+	}
+}
+
+func writeTag(b *strings.Builder, t html.Token, allowed []string) {
+	b.WriteString("<" + t.Data)
+	for _, a := range t.Attr {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		if urlAttrs[a.Key] && !safeURL(a.Val) {
+			continue
+		}
+		b.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+	}
+	if t.Type == html.SelfClosingTagToken {
+		b.WriteString(" /")
+	}
+	b.WriteString(">")
+}
+
+// safeURL 报告地址是否为 http、https、mailto 或相对地址
+func safeURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
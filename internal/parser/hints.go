@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// ttlKey 是 rssTranslator 保存 <ttl> 的 Custom 键
+const ttlKey = "ttl"
+
+// rssTranslator 在默认转换之外保留 RSS 的 <ttl>，通用的 gofeed.Feed 没有对应字段
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *rssTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	if rf, ok := feed.(*rss.Feed); ok && rf.TTL != "" {
+		if result.Custom == nil {
+			result.Custom = make(map[string]string)
+		}
+		result.Custom[ttlKey] = rf.TTL
+	}
+	return result, nil
+}
+
+var syPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// updateInterval 返回 <ttl>（分钟）或 sy:updatePeriod/sy:updateFrequency 声明的更新间隔，
+// 两者都有时取较大值
+func updateInterval(feed *gofeed.Feed) time.Duration {
+	var ttl time.Duration
+	if v, ok := feed.Custom[ttlKey]; ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n > 0 {
+			ttl = time.Duration(n) * time.Minute
+		}
+	}
+	return max(ttl, syInterval(feed))
+}
+
+func syInterval(feed *gofeed.Feed) time.Duration {
+	sy := feed.Extensions["sy"]
+	if sy == nil {
+		return 0
+	}
+	period := syPeriods["daily"]
+	if ext := sy["updatePeriod"]; len(ext) > 0 {
+		p, ok := syPeriods[strings.ToLower(strings.TrimSpace(ext[0].Value))]
+		if !ok {
+			return 0
+		}
+		period = p
+	} else if len(sy["updateFrequency"]) == 0 {
+		return 0
+	}
+	freq := 1
+	if ext := sy["updateFrequency"]; len(ext) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(ext[0].Value)); err == nil && n > 0 {
+			freq = n
+		}
+	}
+	return period / time.Duration(freq)
+}
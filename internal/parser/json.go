@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// legacyTimeLayout 是旧版状态文件中 published 字段的格式
+const legacyTimeLayout = "2006-01-02 15:04:05"
+
+// itemJSON 用指针表示时间，使零值在 JSON 中省略
+type itemJSON struct {
+	*itemFields
+	Published *time.Time `json:"published,omitempty"`
+	Updated   *time.Time `json:"updated,omitempty"`
+}
+
+// itemFields 与 Item 字段相同但没有 JSON 方法，避免递归
+type itemFields Item
+
+// MarshalJSON 在时间为零值时省略 published 和 updated
+func (it Item) MarshalJSON() ([]byte, error) {
+	out := itemJSON{itemFields: (*itemFields)(&it)}
+	if !it.Published.IsZero() {
+		out.Published = &it.Published
+	}
+	if !it.Updated.IsZero() {
+		out.Updated = &it.Updated
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 兼容旧版状态文件中 "2006-01-02 15:04:05" 格式的 published
+func (it *Item) UnmarshalJSON(data []byte) error {
+	var in struct {
+		*itemFields
+		Published string `json:"published"`
+		Updated   string `json:"updated"`
+	}
+	in.itemFields = (*itemFields)(it)
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var err error
+	if it.Published, err = parseTime(in.Published); err != nil {
+		return fmt.Errorf("published: %w", err)
+	}
+	if it.Updated, err = parseTime(in.Updated); err != nil {
+		return fmt.Errorf("updated: %w", err)
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(legacyTimeLayout, s, time.Local)
+}
@@ -1,19 +1,49 @@
 package parser
 
 import (
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/mmcdole/gofeed"
 )
 
 type Item struct {
-	GUID        string
-	Link        string
-	Title       string
-	Description string
-	Published   string
-	Summary     string // AI生成的总结，可选
+	GUID        string        `json:"guid,omitempty"`
+	Link        string        `json:"link,omitempty"`
+	Title       string        `json:"title"`
+	Description string        `json:"description,omitempty"` // 纯文本，最多 200 个字符
+	Published   time.Time     `json:"-"`                     // 见 MarshalJSON
+	Updated     time.Time     `json:"-"`
+	Authors     []string      `json:"authors,omitempty"`
+	Categories  []string      `json:"categories,omitempty"`
+	Enclosures  []Enclosure   `json:"enclosures,omitempty"`
+	Image       string        `json:"image,omitempty"`    // 缩略图或封面图地址
+	Duration    time.Duration `json:"duration,omitempty"` // 播客单集时长，来自 itunes:duration
+	Media       bool          `json:"media,omitempty"`    // 来自媒体模式的订阅源，通知中显示附件时长和大小
+	Summary     string        `json:"summary,omitempty"`  // AI生成的总结，可选
+	// DescriptionHTML 是订阅源中未经处理的描述，供能显示富文本的后端使用。
+	// 与 Content 一起随聚合缓冲区持久化，使延迟发送的条目与立即发送的一致
+	DescriptionHTML string `json:"description_html,omitempty"`
+	// Content 是 content:encoded 或 Atom content 的原始 HTML
+	Content string `json:"content,omitempty"`
+	// FullText 是从原文网页提取的正文，仅用于生成总结，不持久化
+	FullText string `json:"-"`
+}
+
+// Enclosure 是条目附带的媒体文件
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"` // 字节数，未知时为 0
+}
+
+// Feed 是解析后的订阅源
+type Feed struct {
+	Items []*Item
+	// UpdateInterval 是订阅源通过 <ttl> 或 sy:updatePeriod 声明的更新间隔，未声明时为 0
+	UpdateInterval time.Duration
 }
 
 type Parser struct {
@@ -21,12 +51,23 @@ type Parser struct {
 }
 
 func New() *Parser {
+	p := gofeed.NewParser()
+	p.RSSTranslator = &rssTranslator{}
 	return &Parser{
-		parser: gofeed.NewParser(),
+		parser: p,
 	}
 }
 
 func (p *Parser) Parse(data []byte) ([]*Item, error) {
+	feed, err := p.ParseFeed(data)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Items, nil
+}
+
+// ParseFeed 解析订阅源的条目和更新间隔提示
+func (p *Parser) ParseFeed(data []byte) (*Feed, error) {
 	feed, err := p.parser.ParseString(string(data))
 	if err != nil {
 		return nil, err
@@ -35,22 +76,115 @@ func (p *Parser) Parse(data []byte) ([]*Item, error) {
 	items := make([]*Item, 0, len(feed.Items))
 	for _, feedItem := range feed.Items {
 		item := &Item{
-			GUID:        feedItem.GUID,
-			Link:        feedItem.Link,
-			Title:       feedItem.Title,
-			Description: cleanDescription(feedItem.Description),
+			GUID:            feedItem.GUID,
+			Link:            feedItem.Link,
+			Title:           feedItem.Title,
+			Description:     cleanDescription(HTMLToText(feedItem.Description)),
+			DescriptionHTML: feedItem.Description,
+			Content:         feedItem.Content,
+			Categories:      feedItem.Categories,
+			Image:           itemImage(feedItem),
 		}
 
+		if feedItem.UpdatedParsed != nil {
+			item.Updated = *feedItem.UpdatedParsed
+		}
 		if feedItem.PublishedParsed != nil {
-			item.Published = feedItem.PublishedParsed.Format("2006-01-02 15:04:05")
-		} else if feedItem.UpdatedParsed != nil {
-			item.Published = feedItem.UpdatedParsed.Format("2006-01-02 15:04:05")
+			item.Published = *feedItem.PublishedParsed
+		} else {
+			item.Published = item.Updated
+		}
+
+		for _, a := range feedItem.Authors {
+			if a != nil && a.Name != "" {
+				item.Authors = append(item.Authors, a.Name)
+			}
+		}
+
+		if it := feedItem.ITunesExt; it != nil {
+			item.Duration = parseITunesDuration(it.Duration)
+			if len(item.Authors) == 0 && it.Author != "" {
+				item.Authors = []string{it.Author}
+			}
+		}
+
+		for _, e := range feedItem.Enclosures {
+			if e == nil || e.URL == "" {
+				continue
+			}
+			length, _ := strconv.ParseInt(e.Length, 10, 64)
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: length})
+		}
+
+		// 只有摘要时用正文生成描述
+		if item.Description == "" && item.Content != "" {
+			item.Description = cleanDescription(HTMLToText(item.Content))
 		}
 
 		items = append(items, item)
 	}
 
-	return items, nil
+	return &Feed{Items: items, UpdateInterval: updateInterval(feed)}, nil
+}
+
+// itemImage 依次尝试条目图片、media:thumbnail、media:content 和图片附件
+func itemImage(item *gofeed.Item) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	if media, ok := item.Extensions["media"]; ok {
+		for _, name := range []string{"thumbnail", "content"} {
+			for _, ext := range media[name] {
+				if u := ext.Attrs["url"]; u != "" && (name == "thumbnail" || strings.HasPrefix(ext.Attrs["medium"], "image") || strings.HasPrefix(ext.Attrs["type"], "image/")) {
+					return u
+				}
+			}
+		}
+		// media:group 中嵌套的缩略图
+		for _, group := range media["group"] {
+			for _, ext := range group.Children["thumbnail"] {
+				if u := ext.Attrs["url"]; u != "" {
+					return u
+				}
+			}
+		}
+	}
+	for _, e := range item.Enclosures {
+		if e != nil && strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	if item.ITunesExt != nil {
+		return item.ITunesExt.Image
+	}
+	return ""
+}
+
+// MediaEnclosure 返回第一个音频或视频附件，没有时返回 nil
+func (it *Item) MediaEnclosure() *Enclosure {
+	for i, e := range it.Enclosures {
+		if strings.HasPrefix(e.Type, "audio/") || strings.HasPrefix(e.Type, "video/") {
+			return &it.Enclosures[i]
+		}
+	}
+	return nil
+}
+
+// parseITunesDuration 解析 "1:02:03"、"62:03" 或秒数形式的时长，无法解析时返回 0
+func parseITunesDuration(s string) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	var total int64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + int64(n)
+	}
+	return time.Duration(total) * time.Second
 }
 
 func cleanDescription(desc string) string {
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Hello  world", "Hello world"},
+		{"entities", "Tom &amp; Jerry &lt;3 &quot;cheese&quot; &#8212; &nbsp;ok", `Tom & Jerry <3 "cheese" — ok`},
+		{"paragraphs", "<p>First  paragraph.</p>\n\n\n<p>Second<br>line</p>", "First paragraph.\n\nSecond\nline"},
+		{"inline tags", `Read <a href="/x">the <b>docs</b></a>.`, "Read the docs."},
+		{"script and style", "<style>p{color:red}</style><p>Text</p><script>alert('x')</script>", "Text"},
+		{"cdata", "<![CDATA[<p>Inside &amp; out</p>]]>", "Inside & out"},
+		{"list", "<ul><li>One</li><li>Two</li></ul>", "One\nTwo"},
+		{"chinese", "<p>你好，&ldquo;世界&rdquo;</p>", "你好，“世界”"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.in); got != tt.want {
+				t.Errorf("HTMLToText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_HTMLDescription(t *testing.T) {
+	long := strings.Repeat("<p>段落<b>加粗</b>&amp;文字</p>", 40)
+	feed := `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>t</title>
+<item><guid>1</guid><title>One</title><description><![CDATA[` + long + `]]></description></item>
+</channel></rss>`
+
+	items, err := New().Parse([]byte(feed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("len(items) = %d, want 1", len(items))
+	}
+
+	item := items[0]
+	if item.DescriptionHTML != long {
+		t.Errorf("DescriptionHTML was modified")
+	}
+	if strings.ContainsAny(item.Description, "<>") || strings.Contains(item.Description, "&amp;") {
+		t.Errorf("Description contains markup: %q", item.Description)
+	}
+	if !strings.HasPrefix(item.Description, "段落加粗&文字\n\n段落") {
+		t.Errorf("Description = %q", item.Description)
+	}
+	if n := utf8.RuneCountInString(item.Description); n != 203 {
+		t.Errorf("Description has %d runes, want 200 plus ellipsis", n)
+	}
+}
+
+func TestParse_RichFields(t *testing.T) {
+	feed := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"
+     xmlns:media="http://search.yahoo.com/mrss/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>t</title>
+<item>
+  <guid>ep1</guid>
+  <title>Episode 1</title>
+  <link>https://example.com/ep1</link>
+  <dc:creator>Alice</dc:creator>
+  <category>Go</category>
+  <category>Podcast</category>
+  <pubDate>Tue, 05 Nov 2024 08:00:00 +0000</pubDate>
+  <enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="12345678"/>
+  <media:thumbnail url="https://example.com/ep1.jpg"/>
+  <content:encoded><![CDATA[<p>Full <b>show</b> notes</p>]]></content:encoded>
+</item>
+</channel></rss>`
+
+	items, err := New().Parse([]byte(feed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	item := items[0]
+
+	if len(item.Authors) != 1 || item.Authors[0] != "Alice" {
+		t.Errorf("Authors = %v", item.Authors)
+	}
+	if len(item.Categories) != 2 || item.Categories[1] != "Podcast" {
+		t.Errorf("Categories = %v", item.Categories)
+	}
+	want := Enclosure{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg", Length: 12345678}
+	if len(item.Enclosures) != 1 || item.Enclosures[0] != want {
+		t.Errorf("Enclosures = %+v", item.Enclosures)
+	}
+	if item.Image != "https://example.com/ep1.jpg" {
+		t.Errorf("Image = %q", item.Image)
+	}
+	if item.Content != "<p>Full <b>show</b> notes</p>" {
+		t.Errorf("Content = %q", item.Content)
+	}
+	// 没有 description 时用正文生成描述
+	if item.Description != "Full show notes" {
+		t.Errorf("Description = %q", item.Description)
+	}
+	if !item.Published.Equal(time.Date(2024, 11, 5, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Published = %v", item.Published)
+	}
+}
+
+func TestParse_Podcast(t *testing.T) {
+	feed := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel><title>t</title>
+<item>
+  <guid>ep2</guid>
+  <title>Episode 2</title>
+  <itunes:author>Bob</itunes:author>
+  <itunes:duration>1:02:03</itunes:duration>
+  <itunes:image href="https://example.com/ep2.jpg"/>
+  <enclosure url="https://example.com/ep2.jpg" type="image/jpeg"/>
+  <enclosure url="https://example.com/ep2.m4a" type="audio/x-m4a" length="100"/>
+</item>
+<item>
+  <guid>ep3</guid>
+  <title>Episode 3</title>
+  <itunes:duration>754</itunes:duration>
+</item>
+</channel></rss>`
+
+	items, err := New().Parse([]byte(feed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	ep2 := items[0]
+	if ep2.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("Duration = %v", ep2.Duration)
+	}
+	if len(ep2.Authors) != 1 || ep2.Authors[0] != "Bob" {
+		t.Errorf("Authors = %v", ep2.Authors)
+	}
+	if enc := ep2.MediaEnclosure(); enc == nil || enc.URL != "https://example.com/ep2.m4a" {
+		t.Errorf("MediaEnclosure() = %+v", enc)
+	}
+
+	ep3 := items[1]
+	if ep3.Duration != 754*time.Second {
+		t.Errorf("Duration = %v", ep3.Duration)
+	}
+	if enc := ep3.MediaEnclosure(); enc != nil {
+		t.Errorf("MediaEnclosure() = %+v, want nil", enc)
+	}
+
+	// 时长随条目一起持久化
+	data, _ := json.Marshal(ep2)
+	var back Item
+	if err := json.Unmarshal(data, &back); err != nil || back.Duration != ep2.Duration {
+		t.Errorf("round trip Duration = %v, err = %v (%s)", back.Duration, err, data)
+	}
+}
+
+func TestItem_JSON(t *testing.T) {
+	published := time.Date(2024, 11, 5, 8, 0, 0, 0, time.UTC)
+	in := &Item{GUID: "1", Title: "One", Published: published, Categories: []string{"Go"},
+		Content: "<p>x</p>", DescriptionHTML: "<b>d</b>", FullText: "full"}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "updated") || strings.Contains(string(data), "full") {
+		t.Errorf("Marshal() = %s, want zero time and full text omitted", data)
+	}
+
+	var out Item
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !out.Published.Equal(published) || out.Title != "One" || out.Categories[0] != "Go" ||
+		out.Content != "<p>x</p>" || out.DescriptionHTML != "<b>d</b>" {
+		t.Errorf("round trip = %+v", out)
+	}
+
+	// 旧版状态文件中的 published 格式
+	var legacy Item
+	if err := json.Unmarshal([]byte(`{"title":"Old","published":"2024-11-05 16:00:00"}`), &legacy); err != nil {
+		t.Fatalf("Unmarshal(legacy) error = %v", err)
+	}
+	if legacy.Published.Format("2006-01-02 15:04:05") != "2024-11-05 16:00:00" {
+		t.Errorf("legacy Published = %v", legacy.Published)
+	}
+}
+
+func TestParseFeed_UpdateInterval(t *testing.T) {
+	tests := []struct {
+		name, channel string
+		want          time.Duration
+	}{
+		{"none", ``, 0},
+		{"ttl", `<ttl>90</ttl>`, 90 * time.Minute},
+		{"sy", `<sy:updatePeriod>hourly</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency>`, 30 * time.Minute},
+		{"sy period only", `<sy:updatePeriod>daily</sy:updatePeriod>`, 24 * time.Hour},
+		{"larger wins", `<ttl>60</ttl><sy:updatePeriod>hourly</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency>`, time.Hour},
+		{"invalid", `<ttl>soon</ttl><sy:updatePeriod>often</sy:updatePeriod>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+<channel><title>t</title>` + tt.channel + `<item><guid>1</guid><title>One</title></item></channel></rss>`
+
+			got, err := New().ParseFeed([]byte(feed))
+			if err != nil {
+				t.Fatalf("ParseFeed() error = %v", err)
+			}
+			if got.UpdateInterval != tt.want {
+				t.Errorf("UpdateInterval = %v, want %v", got.UpdateInterval, tt.want)
+			}
+			if len(got.Items) != 1 {
+				t.Errorf("Items = %d, want 1", len(got.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skipped 是转换为纯文本时连同内容一起丢弃的元素
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Iframe: true, atom.Svg: true,
+}
+
+// paragraphs 是前后需要空行分隔的块级元素
+var paragraphs = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Figure: true, atom.Hr: true,
+}
+
+// lines 是开始处需要换行的元素
+var lines = map[atom.Atom]bool{
+	atom.Br: true, atom.Li: true, atom.Tr: true, atom.Dt: true, atom.Dd: true,
+	atom.Figcaption: true,
+}
+
+var (
+	spaces   = regexp.MustCompile(`[ \t\r\f\v\p{Zs}]+`)
+	newlines = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText 把 HTML 片段转换为纯文本：解码实体，丢弃脚本和样式，
+// 块级元素之间保留空行，其余空白折叠为一个空格
+func HTMLToText(s string) string {
+	// 部分订阅源把 CDATA 原样嵌在描述中，HTML 解析器会把它当作注释丢弃
+	s = strings.NewReplacer("<![CDATA[", "", "]]>", "").Replace(s)
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return normalize(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch {
+			case skipped[a]:
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case paragraphs[a]:
+				b.WriteString("\n\n")
+			case lines[a] && (tt != html.EndTagToken || a == atom.Br):
+				b.WriteString("\n")
+			}
+		}
+	}
+}
+
+// normalize 折叠每行内的空白，并把连续空行合并为一个
+func normalize(s string) string {
+	ls := strings.Split(s, "\n")
+	for i, l := range ls {
+		ls[i] = strings.TrimSpace(spaces.ReplaceAllString(l, " "))
+	}
+	s = strings.Join(ls, "\n")
+	s = newlines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status 是单个订阅源一次处理的结果状态
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusNotModified Status = "not_modified"
+	StatusFailed      Status = "failed"
+	// StatusSkipped 表示还没到订阅源声明的更新时间或 Retry-After 要求的时间，未抓取
+	StatusSkipped Status = "skipped"
+	// StatusDisabled 表示订阅源曾返回 410 Gone 而被停用
+	StatusDisabled Status = "disabled"
+	// StatusCanceled 表示抓取因程序退出而中断，不计入失败
+	StatusCanceled Status = "canceled"
+)
+
+// FeedResult 是单个订阅源一次处理的统计
+type FeedResult struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// HTTPStatus 是最后一次抓取的状态码，请求未完成时为 0
+	HTTPStatus          int   `json:"http_status,omitempty"`
+	DurationMS          int64 `json:"duration_ms"`
+	ItemsParsed         int   `json:"items_parsed"`
+	NewItems            int   `json:"new_items"`
+	FilteredItems       int   `json:"filtered_items"`
+	SummariesSucceeded  int   `json:"summaries_succeeded"`
+	SummariesFailed     int   `json:"summaries_failed"`
+	NotificationsSent   int   `json:"notifications_sent"`
+	NotificationsFailed int   `json:"notifications_failed"`
+	// 媒体附件下载失败会在下次运行重试，不影响 Status
+	MediaDownloaded     int `json:"media_downloaded,omitempty"`
+	MediaDownloadFailed int `json:"media_download_failed,omitempty"`
+	// MediaSkipped 是媒体模式下因没有音视频附件而跳过的条目数，不计入 FilteredItems
+	MediaSkipped int `json:"media_skipped,omitempty"`
+	// MovedTo 是本次发现的永久重定向目标，之后的运行直接抓取该地址
+	MovedTo string `json:"moved_to,omitempty"`
+	// Alert 是本次发送的健康告警：failing、stale，或恢复时的 recovered
+	Alert string `json:"alert,omitempty"`
+}
+
+// Fail 将结果标记为失败，stage 标明出错的阶段
+func (r *FeedResult) Fail(stage string, err error) {
+	r.Status = StatusFailed
+	r.Error = fmt.Sprintf("%s: %v", stage, err)
+}
+
+// Report 汇总一次运行中所有订阅源的结果，可并发记录
+type Report struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	feeds     map[string]FeedResult
+}
+
+type file struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Feeds      []FeedResult `json:"feeds"`
+}
+
+func New(startedAt time.Time) *Report {
+	return &Report{
+		startedAt: startedAt,
+		feeds:     make(map[string]FeedResult),
+	}
+}
+
+// Record 记录订阅源的结果，同一订阅源只保留最近一次
+func (r *Report) Record(res FeedResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.feeds[res.ID] = res
+}
+
+// Feeds 返回按 ID 排序的结果
+func (r *Report) Feeds() []FeedResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feeds := make([]FeedResult, 0, len(r.feeds))
+	for _, res := range r.feeds {
+		feeds = append(feeds, res)
+	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].ID < feeds[j].ID })
+	return feeds
+}
+
+// Write 以 JSON 格式原子地写入报告，finishedAt 为写入时刻
+func (r *Report) Write(path string, finishedAt time.Time) error {
+	data, err := json.MarshalIndent(file{
+		StartedAt:  r.startedAt,
+		FinishedAt: finishedAt,
+		Feeds:      r.Feeds(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
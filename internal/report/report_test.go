@@ -0,0 +1,49 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReport_Write(t *testing.T) {
+	start := time.Date(2024, 11, 5, 8, 0, 0, 0, time.UTC)
+	r := New(start)
+
+	r.Record(FeedResult{ID: "b", Status: StatusOK, HTTPStatus: 200, ItemsParsed: 10, NewItems: 2, NotificationsSent: 2})
+	r.Record(FeedResult{ID: "a", Status: StatusNotModified, HTTPStatus: 304})
+	failed := FeedResult{ID: "b"}
+	failed.Fail("fetch", errors.New("timeout"))
+	r.Record(failed)
+
+	path := filepath.Join(t.TempDir(), "out", "report.json")
+	if err := r.Write(path, start.Add(time.Minute)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got file
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if !got.FinishedAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("FinishedAt = %v", got.FinishedAt)
+	}
+	if len(got.Feeds) != 2 {
+		t.Fatalf("len(Feeds) = %d, want 2", len(got.Feeds))
+	}
+	if got.Feeds[0].ID != "a" || got.Feeds[0].Status != StatusNotModified || got.Feeds[0].HTTPStatus != 304 {
+		t.Errorf("Feeds[0] = %+v", got.Feeds[0])
+	}
+	// 同一订阅源只保留最近一次结果
+	if got.Feeds[1].Status != StatusFailed || got.Feeds[1].Error != "fetch: timeout" || got.Feeds[1].NewItems != 0 {
+		t.Errorf("Feeds[1] = %+v", got.Feeds[1])
+	}
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Job 是一个按固定间隔重复执行的任务
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context)
+}
+
+type Options struct {
+	// MaxConcurrent 限制同时执行的任务数，<= 0 表示不限制
+	MaxConcurrent int
+	// Jitter 是在间隔上随机增减的比例，例如 0.1 表示 ±10%
+	Jitter float64
+	// AfterRun 在每次任务执行完成后调用
+	AfterRun func(job Job)
+}
+
+// Run 为每个任务启动独立的轮询循环，阻塞直到 ctx 被取消且所有
+// 正在执行的任务结束。任务收到的 context 不会随 ctx 取消，
+// 这样关闭时已开始的抓取和通知可以正常完成。
+func Run(ctx context.Context, jobs []Job, opts Options) {
+	var sem chan struct{}
+	if opts.MaxConcurrent > 0 {
+		sem = make(chan struct{}, opts.MaxConcurrent)
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loop(ctx, job, sem, opts)
+		}()
+	}
+	wg.Wait()
+}
+
+func loop(ctx context.Context, job Job, sem chan struct{}, opts Options) {
+	// 首次执行也加入随机延迟，避免所有任务同时启动
+	delay := time.Duration(rand.Float64() * opts.Jitter * float64(job.Interval))
+
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		job.Run(context.WithoutCancel(ctx))
+		if opts.AfterRun != nil {
+			opts.AfterRun(job)
+		}
+
+		if sem != nil {
+			<-sem
+		}
+
+		delay = NextDelay(job.Interval, opts.Jitter, rand.Float64())
+	}
+}
+
+// NextDelay 返回加入抖动后的等待时间，r 为 [0, 1) 内的随机数
+func NextDelay(interval time.Duration, jitter, r float64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	offset := (r*2 - 1) * jitter * float64(interval)
+	return interval + time.Duration(offset)
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNextDelay(t *testing.T) {
+	interval := 10 * time.Minute
+
+	if got := NextDelay(interval, 0, 0.9); got != interval {
+		t.Errorf("NextDelay() without jitter = %v, want %v", got, interval)
+	}
+	if got := NextDelay(interval, 0.1, 0); got != 9*time.Minute {
+		t.Errorf("NextDelay(r=0) = %v, want 9m", got)
+	}
+	if got := NextDelay(interval, 0.1, 0.5); got != interval {
+		t.Errorf("NextDelay(r=0.5) = %v, want %v", got, interval)
+	}
+}
+
+func TestRun_PollsUntilCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var runs, afterRuns atomic.Int32
+	var mu sync.Mutex
+	inFlight := false
+	finished := false
+
+	jobs := []Job{{
+		Name:     "feed",
+		Interval: 10 * time.Millisecond,
+		Run: func(jobCtx context.Context) {
+			if runs.Add(1) == 3 {
+				mu.Lock()
+				inFlight = true
+				mu.Unlock()
+				cancel()
+				// 取消后正在执行的任务应能继续完成
+				time.Sleep(20 * time.Millisecond)
+				if jobCtx.Err() == nil {
+					mu.Lock()
+					finished = true
+					mu.Unlock()
+				}
+			}
+		},
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, jobs, Options{
+			MaxConcurrent: 1,
+			AfterRun:      func(Job) { afterRuns.Add(1) },
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+
+	if runs.Load() != 3 {
+		t.Errorf("runs = %d, want 3", runs.Load())
+	}
+	if afterRuns.Load() != 3 {
+		t.Errorf("AfterRun calls = %d, want 3", afterRuns.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !inFlight || !finished {
+		t.Error("in-flight job was interrupted by cancellation")
+	}
+}
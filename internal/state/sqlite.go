@@ -0,0 +1,367 @@
+package state
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// defaultHistoryLimit 是每个订阅源默认保留的历史版本数
+const defaultHistoryLimit = 20
+
+// busyTimeout 是等待其他进程释放数据库锁的时间（毫秒）
+const busyTimeout = 5000
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS feeds (
+	feed_id    TEXT PRIMARY KEY,
+	data       TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	feed_id  TEXT NOT NULL,
+	data     TEXT NOT NULL,
+	saved_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_feed ON history (feed_id, id);
+`
+
+// SQLiteStore 把每个订阅源的状态保存为 SQLite 中的一行。
+// 保存在一个写事务中完成，只写入自上次加载或保存以来有变化的订阅源。
+// 写入前重新读取数据库，其他进程在此期间修改过的订阅源会与本进程的改动合并，
+// 因此多个进程可以共用一个数据库。
+type SQLiteStore struct {
+	db *sql.DB
+	// HistoryLimit 是每个订阅源保留的历史版本数，0 表示不保留历史
+	HistoryLimit int
+
+	mu sync.Mutex
+	// saved 是上次加载或保存时各订阅源的 JSON
+	saved map[string]string
+}
+
+// Revision 是订阅源状态的一个历史版本
+type Revision struct {
+	SavedAt time.Time
+	Feed    *Feed
+}
+
+// OpenSQLite 打开 path 处的数据库，不存在时创建
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// WAL 模式允许读写并发，写事务立即加锁以免多个进程升级锁时死锁
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout))
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &SQLiteStore{
+		db:           db,
+		HistoryLimit: defaultHistoryLimit,
+		saved:        make(map[string]string),
+	}, nil
+}
+
+func (st *SQLiteStore) Load() (*State, error) {
+	rows, err := st.db.Query(`SELECT feed_id, data FROM feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := New()
+	saved := make(map[string]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		fs := &Feed{}
+		if err := json.Unmarshal([]byte(data), fs); err != nil {
+			return nil, fmt.Errorf("feed %s: %w", id, err)
+		}
+		s.feeds[id] = fs
+		saved[id] = data
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	st.saved = saved
+	st.mu.Unlock()
+	return s, nil
+}
+
+func (st *SQLiteStore) Save(s *State) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := st.changed(s)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+
+	// 事务立即获取写锁，读取到的数据在提交前不会被其他进程修改
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := readFeeds(tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	written := make(map[string]string, len(changed))
+	for id, data := range changed {
+		if theirs, ok := current[id]; ok && theirs != st.saved[id] {
+			// 其他进程在上次加载后修改了这个订阅源
+			if data, err = mergeFeed(st.saved[id], data, theirs); err != nil {
+				return fmt.Errorf("feed %s: %w", id, err)
+			}
+		}
+		written[id] = data
+		if data == current[id] {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO feeds (feed_id, data, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (feed_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+			id, data, now); err != nil {
+			return err
+		}
+		if err := st.addHistory(tx, id, data, now); err != nil {
+			return err
+		}
+	}
+	// 只有其他进程修改过的订阅源直接采用数据库中的版本
+	for id, data := range current {
+		if _, ok := changed[id]; !ok && data != st.saved[id] {
+			written[id] = data
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for id, data := range written {
+		fs := &Feed{}
+		if err := json.Unmarshal([]byte(data), fs); err != nil {
+			return fmt.Errorf("feed %s: %w", id, err)
+		}
+		s.feeds[id] = fs
+		st.saved[id] = data
+	}
+	return nil
+}
+
+// addHistory 为订阅源记录一个历史版本并删除超出 HistoryLimit 的旧版本。
+// 与上一个版本相比只有健康状况变化时不记录，因为每次抓取都会更新它
+func (st *SQLiteStore) addHistory(tx *sql.Tx, id, data, now string) error {
+	if st.HistoryLimit <= 0 {
+		return nil
+	}
+
+	var last string
+	err := tx.QueryRow(`SELECT data FROM history WHERE feed_id = ? ORDER BY id DESC LIMIT 1`, id).Scan(&last)
+	switch {
+	case err == nil:
+		same, err := sameIgnoringHealth(last, data)
+		if err != nil || same {
+			return err
+		}
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO history (feed_id, data, saved_at) VALUES (?, ?, ?)`, id, data, now); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM history WHERE feed_id = ? AND id NOT IN
+		(SELECT id FROM history WHERE feed_id = ? ORDER BY id DESC LIMIT ?)`,
+		id, id, st.HistoryLimit)
+	return err
+}
+
+// changed 返回与上次加载或保存时不同的订阅源 JSON，调用方需持有 st.mu 和 s.mu
+func (st *SQLiteStore) changed(s *State) (map[string]string, error) {
+	changed := make(map[string]string)
+	for id, fs := range s.feeds {
+		data, err := json.Marshal(fs)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: %w", id, err)
+		}
+		if st.saved[id] != string(data) {
+			changed[id] = string(data)
+		}
+	}
+	return changed, nil
+}
+
+// readFeeds 返回数据库中全部订阅源的 JSON
+func readFeeds(tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.Query(`SELECT feed_id, data FROM feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make(map[string]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		feeds[id] = data
+	}
+	return feeds, rows.Err()
+}
+
+// mergeFeed 以 base 为共同的旧版本合并本进程的 ours 和其他进程的 theirs：
+// 本进程改动过的字段采用 ours，其余字段采用 theirs。
+// 双方都改动了已见条目时取并集，保留较早的首次出现时间。
+func mergeFeed(base, ours, theirs string) (string, error) {
+	var b, o, t map[string]json.RawMessage
+	if base != "" {
+		if err := json.Unmarshal([]byte(base), &b); err != nil {
+			return "", err
+		}
+	}
+	if err := json.Unmarshal([]byte(ours), &o); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(theirs), &t); err != nil {
+		return "", err
+	}
+	if t == nil {
+		t = make(map[string]json.RawMessage)
+	}
+
+	for _, m := range []map[string]json.RawMessage{b, o} {
+		for k := range m {
+			if bytes.Equal(o[k], b[k]) {
+				continue
+			}
+			if k == "seen" && t[k] != nil && !bytes.Equal(t[k], b[k]) {
+				seen, err := unionSeen(o[k], t[k])
+				if err != nil {
+					return "", err
+				}
+				t[k] = seen
+			} else if v, ok := o[k]; ok {
+				t[k] = v
+			} else {
+				delete(t, k)
+			}
+		}
+	}
+
+	// 经 Feed 重新编码，使结果与 changed 中的 JSON 格式一致
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	var fs Feed
+	if err := json.Unmarshal(data, &fs); err != nil {
+		return "", err
+	}
+	data, err = json.Marshal(&fs)
+	return string(data), err
+}
+
+func unionSeen(a, b json.RawMessage) (json.RawMessage, error) {
+	var sa, sb map[string]time.Time
+	if err := json.Unmarshal(a, &sa); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &sb); err != nil {
+		return nil, err
+	}
+	if sa == nil {
+		sa = make(map[string]time.Time, len(sb))
+	}
+	for k, t := range sb {
+		if old, ok := sa[k]; !ok || t.Before(old) {
+			sa[k] = t
+		}
+	}
+	return json.Marshal(sa)
+}
+
+// sameIgnoringHealth 报告两个订阅源 JSON 除健康状况外是否相同
+func sameIgnoringHealth(a, b string) (bool, error) {
+	var fa, fb Feed
+	if err := json.Unmarshal([]byte(a), &fa); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(b), &fb); err != nil {
+		return false, err
+	}
+	fa.Health, fb.Health = nil, nil
+	da, err := json.Marshal(&fa)
+	if err != nil {
+		return false, err
+	}
+	db, err := json.Marshal(&fb)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(da, db), nil
+}
+
+// History 返回订阅源最近保存的 limit 个版本，从新到旧排列，limit <= 0 时返回全部
+func (st *SQLiteStore) History(feedID string, limit int) ([]Revision, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := st.db.Query(`SELECT data, saved_at FROM history WHERE feed_id = ? ORDER BY id DESC LIMIT ?`, feedID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []Revision
+	for rows.Next() {
+		var data, savedAt string
+		if err := rows.Scan(&data, &savedAt); err != nil {
+			return nil, err
+		}
+		rev := Revision{Feed: &Feed{}}
+		if err := json.Unmarshal([]byte(data), rev.Feed); err != nil {
+			return nil, err
+		}
+		if rev.SavedAt, err = time.Parse(time.RFC3339Nano, savedAt); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+func (st *SQLiteStore) Close() error {
+	return st.db.Close()
+}
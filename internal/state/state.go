@@ -4,17 +4,68 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
 )
 
+// currentVersion 是状态文件的格式版本，旧版本为扁平的 map[string]string
+const currentVersion = 2
+
+// Feed 保存单个订阅源的持久化状态
+type Feed struct {
+	// LastSeen 是旧版去重使用的单个 key，迁移到 Seen 后清空
+	LastSeen     string               `json:"last_seen,omitempty"`
+	Seen         map[string]time.Time `json:"seen,omitempty"`
+	ETag         string               `json:"etag,omitempty"`
+	LastModified string               `json:"last_modified,omitempty"`
+	// Pending 是聚合窗口内尚未发送的条目，PendingSince 为窗口开始时间
+	Pending      []*parser.Item `json:"pending,omitempty"`
+	PendingSince *time.Time     `json:"pending_since,omitempty"`
+	// DiscoveredURL 是从网站地址自动发现的订阅源地址
+	DiscoveredURL string `json:"discovered_url,omitempty"`
+	// Downloads 是等待下载的媒体附件，失败时保留到下次运行重试
+	Downloads []parser.Enclosure `json:"downloads,omitempty"`
+	// UpdateInterval 是订阅源最近一次声明的更新间隔，NextPoll 之前不再抓取
+	UpdateInterval time.Duration `json:"update_interval,omitempty"`
+	NextPoll       *time.Time    `json:"next_poll,omitempty"`
+	// DisabledURL 是返回 410 Gone 而被停用的配置地址，配置中的地址改变后自动恢复
+	DisabledURL string     `json:"disabled_url,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	// MovedTo 是配置地址 MovedFrom 永久重定向到的新地址，之后直接抓取新地址
+	MovedFrom string `json:"moved_from,omitempty"`
+	MovedTo   string `json:"moved_to,omitempty"`
+	// Health 用于连续失败和长期无更新的告警
+	Health *Health `json:"health,omitempty"`
+}
+
+// Health 是订阅源抓取和解析的健康状况
+type Health struct {
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	// LastNewItem 是最近一次出现新条目的时间，开始跟踪时以首次成功抓取的时间为准
+	LastNewItem *time.Time `json:"last_new_item,omitempty"`
+	// Alert 是已发送且尚未恢复的告警类型，为空表示正常
+	Alert string `json:"alert,omitempty"`
+}
+
 type State struct {
-	mu     sync.RWMutex
-	states map[string]string
+	mu    sync.RWMutex
+	feeds map[string]*Feed
+}
+
+type file struct {
+	Version int              `json:"version"`
+	Feeds   map[string]*Feed `json:"feeds"`
 }
 
 func New() *State {
 	return &State{
-		states: make(map[string]string),
+		feeds: make(map[string]*Feed),
 	}
 }
 
@@ -34,23 +85,366 @@ func Load(path string) (*State, error) {
 		return s, nil
 	}
 
-	if err := json.Unmarshal(data, &s.states); err != nil {
+	if err := s.decode(data); err != nil {
 		return nil, err
 	}
 
 	return s, nil
 }
 
+func (s *State) decode(data []byte) error {
+	var probe struct {
+		Version int `json:"version"`
+	}
+	// 旧格式中 version 不存在或不是数字，探测失败时按旧格式处理
+	if json.Unmarshal(data, &probe) == nil && probe.Version > 0 {
+		var f file
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		for id, fs := range f.Feeds {
+			if fs != nil {
+				s.feeds[id] = fs
+			}
+		}
+		return nil
+	}
+
+	// 旧格式：feedID -> 最后一次看到的条目 key
+	var legacy map[string]string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	for id, lastSeen := range legacy {
+		s.feeds[id] = &Feed{LastSeen: lastSeen}
+	}
+	return nil
+}
+
+// feed 返回指定订阅源的状态，不存在时创建，调用方需持有写锁
+func (s *State) feed(feedID string) *Feed {
+	fs, ok := s.feeds[feedID]
+	if !ok {
+		fs = &Feed{}
+		s.feeds[feedID] = fs
+	}
+	return fs
+}
+
+// Len 返回有状态的订阅源数量
+func (s *State) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.feeds)
+}
+
 func (s *State) Get(feedID string) string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.states[feedID]
+	if fs, ok := s.feeds[feedID]; ok {
+		return fs.LastSeen
+	}
+	return ""
 }
 
 func (s *State) Set(feedID, value string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.states[feedID] = value
+	s.feed(feedID).LastSeen = value
+}
+
+// SeenKeys 返回已见过的条目 key 及其首次出现时间的副本
+func (s *State) SeenKeys(feedID string) map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fs, ok := s.feeds[feedID]
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]time.Time, len(fs.Seen))
+	for k, t := range fs.Seen {
+		seen[k] = t
+	}
+	return seen
+}
+
+// MarkSeen 将 keys 中尚未见过的 key 标记为在 at 时刻首次出现，并只保留最近的 limit 个 key。
+// 本次传入的 key 总是保留，即使数量超过 limit。已见过的 key 不更新时间，
+// 因此订阅源的条目没有变化时状态也不变。
+func (s *State) MarkSeen(feedID string, keys []string, at time.Time, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	if fs.Seen == nil {
+		fs.Seen = make(map[string]time.Time, len(keys))
+	}
+	current := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		current[k] = true
+		if _, ok := fs.Seen[k]; !ok {
+			fs.Seen[k] = at
+		}
+	}
+
+	if limit < len(current) {
+		limit = len(current)
+	}
+	if len(fs.Seen) <= limit {
+		return
+	}
+
+	// 先保留本次出现的 key，再按首次出现时间从新到旧保留
+	type entry struct {
+		key     string
+		at      time.Time
+		current bool
+	}
+	entries := make([]entry, 0, len(fs.Seen))
+	for k, t := range fs.Seen {
+		entries = append(entries, entry{k, t, current[k]})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].current != entries[j].current {
+			return entries[i].current
+		}
+		if !entries[i].at.Equal(entries[j].at) {
+			return entries[i].at.After(entries[j].at)
+		}
+		return entries[i].key < entries[j].key
+	})
+	for _, e := range entries[limit:] {
+		delete(fs.Seen, e.key)
+	}
+}
+
+// AddPending 把条目加入聚合缓冲区，返回缓冲区开始时间
+func (s *State) AddPending(feedID string, items []*parser.Item, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	if fs.PendingSince == nil {
+		fs.PendingSince = &now
+	}
+	fs.Pending = append(fs.Pending, items...)
+	return *fs.PendingSince
+}
+
+// Pending 返回聚合缓冲区中的条目和缓冲区开始时间
+func (s *State) Pending(feedID string) ([]*parser.Item, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fs, ok := s.feeds[feedID]
+	if !ok || fs.PendingSince == nil {
+		return nil, time.Time{}
+	}
+	return append([]*parser.Item(nil), fs.Pending...), *fs.PendingSince
+}
+
+func (s *State) ClearPending(feedID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		fs.Pending = nil
+		fs.PendingSince = nil
+	}
+}
+
+// AddDownloads 把附件加入下载队列，已在队列中的地址会被忽略
+func (s *State) AddDownloads(feedID string, encs []parser.Enclosure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	for _, enc := range encs {
+		if !containsEnclosure(fs.Downloads, enc.URL) {
+			fs.Downloads = append(fs.Downloads, enc)
+		}
+	}
+}
+
+// Downloads 返回下载队列
+func (s *State) Downloads(feedID string) []parser.Enclosure {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		return append([]parser.Enclosure(nil), fs.Downloads...)
+	}
+	return nil
+}
+
+// RemoveDownload 把已完成或无法下载的附件移出队列
+func (s *State) RemoveDownload(feedID, u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs, ok := s.feeds[feedID]
+	if !ok {
+		return
+	}
+	kept := fs.Downloads[:0]
+	for _, enc := range fs.Downloads {
+		if enc.URL != u {
+			kept = append(kept, enc)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	fs.Downloads = kept
+}
+
+func containsEnclosure(encs []parser.Enclosure, u string) bool {
+	for _, enc := range encs {
+		if enc.URL == u {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) DiscoveredURL(feedID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		return fs.DiscoveredURL
+	}
+	return ""
+}
+
+// SetDiscoveredURL 记录自动发现的订阅源地址，u 为空时清除。
+// 地址变化时原有的条件请求校验值不再适用，一并清除。
+func (s *State) SetDiscoveredURL(feedID, u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	if fs.DiscoveredURL != u {
+		fs.DiscoveredURL = u
+		fs.ETag = ""
+		fs.LastModified = ""
+	}
+}
+
+// UpdateInterval 返回订阅源最近一次声明的更新间隔
+func (s *State) UpdateInterval(feedID string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		return fs.UpdateInterval
+	}
+	return 0
+}
+
+func (s *State) SetUpdateInterval(feedID string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feed(feedID).UpdateInterval = d
+}
+
+// NextPoll 返回下次允许抓取的最早时间，零值表示不限制
+func (s *State) NextPoll(feedID string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok && fs.NextPoll != nil {
+		return *fs.NextPoll
+	}
+	return time.Time{}
+}
+
+// SetNextPoll 设置下次允许抓取的最早时间，t 为零值时清除
+func (s *State) SetNextPoll(feedID string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	if t.IsZero() {
+		fs.NextPoll = nil
+	} else {
+		fs.NextPoll = &t
+	}
+}
+
+// Health 返回订阅源的健康状况
+func (s *State) Health(feedID string) Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok && fs.Health != nil {
+		return *fs.Health
+	}
+	return Health{}
+}
+
+func (s *State) SetHealth(feedID string, h Health) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feed(feedID).Health = &h
+}
+
+// Moved 返回订阅源从配置地址 from 永久迁移到的地址 to，没有迁移时都为空
+func (s *State) Moved(feedID string) (from, to string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		return fs.MovedFrom, fs.MovedTo
+	}
+	return "", ""
+}
+
+// SetMoved 记录订阅源的永久迁移，to 为空时清除
+func (s *State) SetMoved(feedID, from, to string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	if to == "" {
+		from = ""
+	}
+	fs.MovedFrom = from
+	fs.MovedTo = to
+}
+
+// Disabled 返回订阅源被停用时的地址和时间，未停用时 u 为空
+func (s *State) Disabled(feedID string) (u string, at time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok && fs.DisabledURL != "" {
+		if fs.DisabledAt != nil {
+			at = *fs.DisabledAt
+		}
+		return fs.DisabledURL, at
+	}
+	return "", time.Time{}
+}
+
+// Disable 停用订阅源，u 是停用时配置中的地址
+func (s *State) Disable(feedID, u string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	fs.DisabledURL = u
+	fs.DisabledAt = &at
+}
+
+func (s *State) Enable(feedID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		fs.DisabledURL = ""
+		fs.DisabledAt = nil
+	}
+}
+
+// Validators 返回用于条件请求的 ETag 和 Last-Modified
+func (s *State) Validators(feedID string) (etag, lastModified string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fs, ok := s.feeds[feedID]; ok {
+		return fs.ETag, fs.LastModified
+	}
+	return "", ""
+}
+
+func (s *State) SetValidators(feedID, etag, lastModified string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs := s.feed(feedID)
+	fs.ETag = etag
+	fs.LastModified = lastModified
 }
 
 func (s *State) Save(path string) error {
@@ -61,7 +455,7 @@ func (s *State) Save(path string) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(s.states, "", "  ")
+	data, err := json.MarshalIndent(file{Version: currentVersion, Feeds: s.feeds}, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/parser"
 )
 
 func TestState_GetSet(t *testing.T) {
@@ -85,3 +88,207 @@ func TestState_LoadEmpty(t *testing.T) {
 		t.Fatal("Load() returned nil state")
 	}
 }
+
+func TestState_Validators(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s1 := New()
+	s1.Set("feed1", "item1")
+	s1.SetValidators("feed1", `"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT")
+
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	etag, lastModified := s2.Validators("feed1")
+	if etag != `"abc"` || lastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("Validators() = %q, %q", etag, lastModified)
+	}
+	if got := s2.Get("feed1"); got != "item1" {
+		t.Errorf("Get(feed1) = %v, want item1", got)
+	}
+}
+
+func TestState_LoadLegacy(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "legacy.json")
+
+	legacy := `{"feed1": "item1", "version": "item2"}`
+	if err := os.WriteFile(statePath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to create legacy file: %v", err)
+	}
+
+	s, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := s.Get("feed1"); got != "item1" {
+		t.Errorf("Get(feed1) = %v, want item1", got)
+	}
+	if got := s.Get("version"); got != "item2" {
+		t.Errorf("Get(version) = %v, want item2", got)
+	}
+}
+
+func TestState_Pending(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	s1 := New()
+	s1.AddPending("feed1", []*parser.Item{{GUID: "a", Title: "A"}}, start)
+	since := s1.AddPending("feed1", []*parser.Item{{GUID: "b", Title: "B"}}, start.Add(time.Hour))
+	if !since.Equal(start) {
+		t.Errorf("AddPending() since = %v, want %v", since, start)
+	}
+
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	items, since := s2.Pending("feed1")
+	if len(items) != 2 || items[0].GUID != "a" || items[1].Title != "B" {
+		t.Errorf("Pending() items = %+v", items)
+	}
+	if !since.Equal(start) {
+		t.Errorf("Pending() since = %v, want %v", since, start)
+	}
+
+	s2.ClearPending("feed1")
+	if items, since := s2.Pending("feed1"); len(items) != 0 || !since.IsZero() {
+		t.Errorf("Pending() after clear = %v, %v", items, since)
+	}
+}
+
+func TestState_Downloads(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s1 := New()
+	s1.AddDownloads("pod", []parser.Enclosure{{URL: "https://cdn/1.mp3", Length: 10}, {URL: "https://cdn/2.mp3"}})
+	s1.AddDownloads("pod", []parser.Enclosure{{URL: "https://cdn/1.mp3"}})
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got := s2.Downloads("pod")
+	if len(got) != 2 || got[0].Length != 10 {
+		t.Fatalf("Downloads() = %+v", got)
+	}
+
+	s2.RemoveDownload("pod", "https://cdn/1.mp3")
+	if got := s2.Downloads("pod"); len(got) != 1 || got[0].URL != "https://cdn/2.mp3" {
+		t.Errorf("Downloads() after remove = %+v", got)
+	}
+}
+
+func TestState_NextPoll(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	next := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	s1 := New()
+	s1.SetUpdateInterval("feed1", time.Hour)
+	s1.SetNextPoll("feed1", next)
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := s2.NextPoll("feed1"); !got.Equal(next) {
+		t.Errorf("NextPoll() = %v, want %v", got, next)
+	}
+	if got := s2.UpdateInterval("feed1"); got != time.Hour {
+		t.Errorf("UpdateInterval() = %v, want 1h", got)
+	}
+
+	s2.SetNextPoll("feed1", time.Time{})
+	if got := s2.NextPoll("feed1"); !got.IsZero() {
+		t.Errorf("NextPoll() after clear = %v", got)
+	}
+}
+
+func TestState_Disable(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	at := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	s1 := New()
+	s1.Disable("feed1", "https://example.com/feed.xml", at)
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if u, got := s2.Disabled("feed1"); u != "https://example.com/feed.xml" || !got.Equal(at) {
+		t.Errorf("Disabled() = %q, %v", u, got)
+	}
+
+	s2.Enable("feed1")
+	if u, _ := s2.Disabled("feed1"); u != "" {
+		t.Errorf("Disabled() after Enable = %q", u)
+	}
+}
+
+func TestState_Moved(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s1 := New()
+	s1.SetMoved("feed1", "http://example.com/rss", "https://example.com/feed.xml")
+	if err := s1.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if from, to := s2.Moved("feed1"); from != "http://example.com/rss" || to != "https://example.com/feed.xml" {
+		t.Errorf("Moved() = %q, %q", from, to)
+	}
+
+	s2.SetMoved("feed1", "", "")
+	if from, to := s2.Moved("feed1"); from != "" || to != "" {
+		t.Errorf("Moved() after clear = %q, %q", from, to)
+	}
+}
+
+func TestState_MarkSeenUnchanged(t *testing.T) {
+	s := New()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.MarkSeen("feed", []string{"a", "b"}, t0, 10)
+
+	// 条目没有变化时不更新时间，状态保持不变
+	s.MarkSeen("feed", []string{"b", "a"}, t0.Add(time.Hour), 10)
+	seen := s.SeenKeys("feed")
+	if !seen["a"].Equal(t0) || !seen["b"].Equal(t0) {
+		t.Errorf("seen = %v, want both at %v", seen, t0)
+	}
+
+	// 超过上限时先保留本次出现的 key，即使它们更早出现
+	s.MarkSeen("feed", []string{"c"}, t0.Add(2*time.Hour), 10)
+	s.MarkSeen("feed", []string{"a", "b"}, t0.Add(3*time.Hour), 2)
+	seen = s.SeenKeys("feed")
+	if _, ok := seen["c"]; ok || len(seen) != 2 {
+		t.Errorf("seen = %v, want a and b", seen)
+	}
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Store 是状态的持久化后端
+type Store interface {
+	Load() (*State, error)
+	Save(s *State) error
+	Close() error
+}
+
+// sqlitePrefix 是 SQLite 存储地址的前缀
+const sqlitePrefix = "sqlite:"
+
+// Open 按地址打开状态存储：sqlite:<path> 为 SQLite 数据库，
+// file:<path> 或不带前缀的路径为 JSON 文件
+func Open(dsn string) (Store, error) {
+	switch {
+	case strings.HasPrefix(dsn, sqlitePrefix):
+		return OpenSQLite(strings.TrimPrefix(dsn, sqlitePrefix))
+	case strings.HasPrefix(dsn, "file:"):
+		return &FileStore{Path: strings.TrimPrefix(dsn, "file:")}, nil
+	case dsn == "":
+		return nil, fmt.Errorf("empty state location")
+	default:
+		return &FileStore{Path: dsn}, nil
+	}
+}
+
+// FileStore 把状态整体保存为一个 JSON 文件
+type FileStore struct {
+	Path string
+}
+
+func (f *FileStore) Load() (*State, error) {
+	return Load(f.Path)
+}
+
+func (f *FileStore) Save(s *State) error {
+	return s.Save(f.Path)
+}
+
+func (f *FileStore) Close() error {
+	return nil
+}
+
+// Migrate 把 from 中的全部状态写入 to，返回复制的订阅源数量。
+// to 中已有但 from 中没有的订阅源保持不变。
+func Migrate(from, to Store) (int, error) {
+	s, err := from.Load()
+	if err != nil {
+		return 0, fmt.Errorf("load: %w", err)
+	}
+	dst, err := to.Load()
+	if err != nil {
+		return 0, fmt.Errorf("load: %w", err)
+	}
+	for id, fs := range s.feeds {
+		dst.feeds[id] = fs
+	}
+	if err := to.Save(dst); err != nil {
+		return 0, fmt.Errorf("save: %w", err)
+	}
+	return s.Len(), nil
+}
@@ -0,0 +1,234 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		dsn    string
+		sqlite bool
+	}{
+		{filepath.Join(dir, "state.json"), false},
+		{"file:" + filepath.Join(dir, "state.json"), false},
+		{"sqlite:" + filepath.Join(dir, "state.db"), true},
+	}
+	for _, tt := range tests {
+		st, err := Open(tt.dsn)
+		if err != nil {
+			t.Fatalf("Open(%q) error = %v", tt.dsn, err)
+		}
+		if _, ok := st.(*SQLiteStore); ok != tt.sqlite {
+			t.Errorf("Open(%q) = %T", tt.dsn, st)
+		}
+		st.Close()
+	}
+
+	if _, err := Open(""); err == nil {
+		t.Error("Open(\"\"): expected error")
+	}
+}
+
+func TestSQLiteStore_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	st, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	s1 := New()
+	s1.Set("feed1", "item1")
+	s1.SetValidators("feed2", `"v1"`, "")
+	if err := st.Save(s1); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s2, err := st.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := s2.Get("feed1"); got != "item1" {
+		t.Errorf("Get(feed1) = %v, want item1", got)
+	}
+	if etag, _ := s2.Validators("feed2"); etag != `"v1"` {
+		t.Errorf("etag = %q", etag)
+	}
+}
+
+func TestSQLiteStore_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	a, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer a.Close()
+	b, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer b.Close()
+
+	// 两个进程各自加载全部状态，但只修改自己负责的订阅源
+	sa, _ := a.Load()
+	sb, _ := b.Load()
+	sa.Set("feed1", "a")
+	sb.Set("feed2", "b")
+	if err := a.Save(sa); err != nil {
+		t.Fatalf("Save(a) error = %v", err)
+	}
+	if err := b.Save(sb); err != nil {
+		t.Fatalf("Save(b) error = %v", err)
+	}
+
+	s, err := a.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Get("feed1") != "a" || s.Get("feed2") != "b" {
+		t.Errorf("feed1 = %q, feed2 = %q, want a, b", s.Get("feed1"), s.Get("feed2"))
+	}
+}
+
+func TestSQLiteStore_Merge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	a, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer a.Close()
+	b, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer b.Close()
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := New()
+	s.MarkSeen("feed1", []string{"k1"}, t0, 10)
+	if err := a.Save(s); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// 两个进程都修改了 feed1：b 看到新条目，a 看到另一条新条目并更新健康状况
+	sa, _ := a.Load()
+	sb, _ := b.Load()
+	sb.MarkSeen("feed1", []string{"k1", "k2"}, t0.Add(time.Hour), 10)
+	sb.Set("feed2", "b")
+	if err := b.Save(sb); err != nil {
+		t.Fatalf("Save(b) error = %v", err)
+	}
+	sa.MarkSeen("feed1", []string{"k1", "k3"}, t0.Add(2*time.Hour), 10)
+	sa.SetHealth("feed1", Health{ConsecutiveFailures: 2})
+	if err := a.Save(sa); err != nil {
+		t.Fatalf("Save(a) error = %v", err)
+	}
+
+	check := func(name string, s *State) {
+		seen := s.SeenKeys("feed1")
+		for _, k := range []string{"k1", "k2", "k3"} {
+			if _, ok := seen[k]; !ok {
+				t.Errorf("%s: key %q lost, seen = %v", name, k, seen)
+			}
+		}
+		if !seen["k1"].Equal(t0) {
+			t.Errorf("%s: k1 first seen = %v, want %v", name, seen["k1"], t0)
+		}
+		if h := s.Health("feed1"); h.ConsecutiveFailures != 2 {
+			t.Errorf("%s: health = %+v", name, h)
+		}
+		if s.Get("feed2") != "b" {
+			t.Errorf("%s: feed2 = %q, want b", name, s.Get("feed2"))
+		}
+	}
+	// 保存后 a 的内存状态也包含 b 的改动
+	check("in memory", sa)
+	got, err := b.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	check("reloaded", got)
+}
+
+func TestSQLiteStore_History(t *testing.T) {
+	st, err := OpenSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	st.HistoryLimit = 2
+
+	s := New()
+	for _, v := range []string{"1", "2", "2", "3"} {
+		s.Set("feed1", v)
+		if err := st.Save(s); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// 只有健康状况变化时不产生新版本
+	s.SetHealth("feed1", Health{ConsecutiveFailures: 1})
+	if err := st.Save(s); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// 未变化的保存不产生新版本，超出 HistoryLimit 的旧版本被删除
+	revs, err := st.History("feed1", 0)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(revs) != 2 || revs[0].Feed.LastSeen != "3" || revs[1].Feed.LastSeen != "2" {
+		t.Fatalf("History() = %+v", revs)
+	}
+	if revs[0].SavedAt.IsZero() {
+		t.Error("SavedAt is zero")
+	}
+
+	if revs, _ := st.History("feed1", 1); len(revs) != 1 {
+		t.Errorf("History(limit 1) returned %d revisions", len(revs))
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	src := &FileStore{Path: filepath.Join(dir, "state.json")}
+	s := New()
+	s.Set("feed1", "item1")
+	s.Set("feed2", "item2")
+	if err := src.Save(s); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	db, err := OpenSQLite(filepath.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer db.Close()
+
+	// 目标中已有但源中没有的订阅源保持不变
+	for _, dst := range []Store{db, &FileStore{Path: filepath.Join(dir, "copy.json")}} {
+		existing := New()
+		existing.Set("feed1", "old")
+		existing.Set("feed3", "item3")
+		if err := dst.Save(existing); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		n, err := Migrate(src, dst)
+		if err != nil || n != 2 {
+			t.Fatalf("Migrate(%T) = %d, %v", dst, n, err)
+		}
+
+		got, err := dst.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got.Get("feed1") != "item1" || got.Get("feed2") != "item2" || got.Get("feed3") != "item3" {
+			t.Errorf("%T: migrated state = %q, %q, %q", dst, got.Get("feed1"), got.Get("feed2"), got.Get("feed3"))
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package summarizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 把总结结果按内容哈希保存在磁盘上，避免重复调用 API。
+// 每个条目一个文件，超过 ttl 的条目视为失效，条目数超过 maxEntries
+// 时删除最旧的条目。
+type Cache struct {
+	dir        string
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu sync.Mutex
+}
+
+type cacheEntry struct {
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewCache 创建缓存，ttl 或 maxEntries <= 0 表示不限制
+func NewCache(dir string, ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		dir:        dir,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// CacheKey 返回条目的缓存 key，由模型、提示词模板和标题、描述的哈希组成，
+// 任何一项变化都会使旧的缓存失效
+func (s *Summarizer) CacheKey(title, description string) string {
+	content := sha256.Sum256([]byte(title + "\x00" + description))
+	key := sha256.Sum256([]byte(s.model + "\x00" + promptTemplate + "\x00" + hex.EncodeToString(content[:])))
+	return hex.EncodeToString(key[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Summary == "" {
+		os.Remove(c.path(key))
+		return "", false
+	}
+
+	if c.ttl > 0 && c.now().Sub(entry.CreatedAt) > c.ttl {
+		os.Remove(c.path(key))
+		return "", false
+	}
+
+	return entry.Summary, true
+}
+
+func (c *Cache) Put(key, summary string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cacheEntry{Summary: summary, CreatedAt: c.now()})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	return c.prune()
+}
+
+// prune 删除过期条目，并在超出数量上限时按修改时间删除最旧的条目
+func (c *Cache) prune() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type file struct {
+		name    string
+		modTime time.Time
+	}
+	files := make([]file, 0, len(entries))
+	now := c.now()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if c.ttl > 0 && now.Sub(info.ModTime()) > c.ttl {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+			continue
+		}
+		files = append(files, file{e.Name(), info.ModTime()})
+	}
+
+	if c.maxEntries <= 0 || len(files) <= c.maxEntries {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files[:len(files)-c.maxEntries] {
+		os.Remove(filepath.Join(c.dir, f.name))
+	}
+	return nil
+}
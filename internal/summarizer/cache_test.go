@@ -0,0 +1,85 @@
+package summarizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_GetPut(t *testing.T) {
+	c := NewCache(t.TempDir(), time.Hour, 10)
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("Get() on empty cache = ok")
+	}
+
+	if err := c.Put("k1", "总结"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, ok := c.Get("k1"); !ok || got != "总结" {
+		t.Errorf("Get() = %q, %v; want 总结, true", got, ok)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := NewCache(t.TempDir(), time.Hour, 10)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.Put("k1", "summary")
+	now = now.Add(2 * time.Hour)
+
+	if _, ok := c.Get("k1"); ok {
+		t.Error("Get() returned expired entry")
+	}
+	if _, err := os.Stat(c.path("k1")); !os.IsNotExist(err) {
+		t.Error("expired entry not removed from disk")
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(dir, 0, 2)
+
+	for i, key := range []string{"a", "b", "c"} {
+		if err := c.Put(key, "summary "+key); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		// 保证修改时间递增
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(dir, key+".json"), mtime, mtime)
+	}
+	c.Put("d", "summary d")
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("cache has %d entries, want 2", len(entries))
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("oldest entry %q not evicted", key)
+		}
+	}
+	if _, ok := c.Get("d"); !ok {
+		t.Error("newest entry evicted")
+	}
+}
+
+func TestSummarizer_CacheKey(t *testing.T) {
+	a := &Summarizer{model: "gpt-a"}
+	b := &Summarizer{model: "gpt-b"}
+
+	if a.CacheKey("t", "d") != a.CacheKey("t", "d") {
+		t.Error("CacheKey() not deterministic")
+	}
+	if a.CacheKey("t", "d") == b.CacheKey("t", "d") {
+		t.Error("CacheKey() ignores model")
+	}
+	if a.CacheKey("t", "d") == a.CacheKey("t", "d2") {
+		t.Error("CacheKey() ignores description")
+	}
+	if a.CacheKey("ab", "c") == a.CacheKey("a", "bc") {
+		t.Error("CacheKey() does not separate title and description")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/rsswatcher/rsswatcher/internal/metrics"
 )
 
 const (
@@ -17,6 +19,20 @@ const (
 	maxContentLen  = 8000 // 限制输入内容长度，避免超出模型限制
 )
 
+// promptTemplate 是总结使用的提示词，参数依次为标题和正文
+const promptTemplate = `请为以下文章生成一个简洁的中文总结，要求：
+1. 总结长度控制在100字以内
+2. 突出文章的核心观点和关键信息
+3. 使用简洁明了的语言
+4. 如果原文是英文，请翻译成中文
+
+文章标题：%s
+
+文章内容：
+%s
+
+请直接输出总结内容，不要添加任何前缀或说明。`
+
 type Summarizer struct {
 	apiEndpoint string
 	apiKey      string
@@ -47,6 +63,10 @@ type APIResponse struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
 }
 
 func New() *Summarizer {
@@ -76,6 +96,15 @@ func (s *Summarizer) Summarize(ctx context.Context, title, description string) (
 		return "", fmt.Errorf("summarizer is not enabled")
 	}
 
+	metrics.SummarizerRequests.Inc()
+	summary, err := s.summarize(ctx, title, description)
+	if err != nil {
+		metrics.SummarizerFailures.Inc()
+	}
+	return summary, err
+}
+
+func (s *Summarizer) summarize(ctx context.Context, title, description string) (string, error) {
 	// 构建提示词
 	content := buildPrompt(title, description)
 
@@ -148,6 +177,11 @@ func (s *Summarizer) Summarize(ctx context.Context, title, description string) (
 		return "", fmt.Errorf("failed to parse response (invalid JSON): %w (response length: %d bytes)", err, len(body))
 	}
 
+	if apiResp.Usage != nil {
+		metrics.SummarizerTokens.Add(float64(apiResp.Usage.PromptTokens), "prompt")
+		metrics.SummarizerTokens.Add(float64(apiResp.Usage.CompletionTokens), "completion")
+	}
+
 	// 检查错误字段
 	if apiResp.Error != nil {
 		return "", fmt.Errorf("API error: %s (type: %s)", apiResp.Error.Message, apiResp.Error.Type)
@@ -178,18 +212,7 @@ func buildPrompt(title, description string) string {
 		content = string(runes[:maxContentLen]) + "..."
 	}
 
-	prompt := fmt.Sprintf(`请为以下文章生成一个简洁的中文总结，要求：
-1. 总结长度控制在100字以内
-2. 突出文章的核心观点和关键信息
-3. 使用简洁明了的语言
-4. 如果原文是英文，请翻译成中文
-
-文章标题：%s
-
-文章内容：
-%s
-
-请直接输出总结内容，不要添加任何前缀或说明。`, title, content)
+	prompt := fmt.Sprintf(promptTemplate, title, content)
 
 	return prompt
 }